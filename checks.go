@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// errFindings is returned by run when a policy check fails. main maps it to
+// exit code 2 so that CI can tell findings apart from operational errors.
+var errFindings = errors.New("policy violations found")
+
+// deprecatedTLS describes every host/port that still offers SSLv3, TLS 1.0
+// or 1.1.
+func deprecatedTLS(hosts sslparse.Hosts) []string {
+	var offenders []string
+	for _, host := range hosts.Hosts {
+		for _, port := range host.Ports {
+			for _, v := range port.TLS.Versions() {
+				if v.Deprecated() && len(v.Data.Ciphers) > 0 {
+					offenders = append(offenders, fmt.Sprintf("%s:%d offers %s", host.IP, port.ID, v.Name))
+				}
+			}
+		}
+	}
+	return offenders
+}
+
+// Rule identifiers for findings, shared by the report formats that
+// categorise them.
+const (
+	ruleDeprecatedTLS    = "deprecated-tls"
+	ruleWeakCipher       = "weak-cipher"
+	ruleDisallowedCipher = "disallowed-cipher"
+	ruleBelowMinTLS      = "below-min-tls"
+)
+
+// finding is a single problem detected on a port.
+type finding struct {
+	rule    string
+	message string
+}
+
+// portFindings reports every deprecated TLS version, weak cipher,
+// disallowed cipher and version below -min-tls offered on port.
+func portFindings(port sslparse.Port) []finding {
+	var findings []finding
+	for _, v := range port.BelowMinTLS {
+		findings = append(findings, finding{ruleBelowMinTLS, "offers " + v + ", below the minimum TLS version"})
+	}
+	for _, v := range port.TLS.Versions() {
+		if v.Deprecated() && len(v.Data.Ciphers) > 0 {
+			findings = append(findings, finding{ruleDeprecatedTLS, "offers deprecated " + v.Name})
+		}
+		for _, cipher := range v.Data.WeakCiphers {
+			findings = append(findings, finding{ruleWeakCipher, fmt.Sprintf("offers weak cipher %s with %s", cipher, v.Name)})
+		}
+		for _, cipher := range v.Data.DisallowedCiphers {
+			findings = append(findings, finding{ruleDisallowedCipher, fmt.Sprintf("offers disallowed cipher %s with %s", cipher, v.Name)})
+		}
+	}
+	return findings
+}
+
+// portIssues returns the messages of portFindings.
+func portIssues(port sslparse.Port) []string {
+	var issues []string
+	for _, f := range portFindings(port) {
+		issues = append(issues, f.message)
+	}
+	return issues
+}
+
+// hasDeprecatedTLS reports whether any port of hosts offers SSLv3, TLS 1.0
+// or 1.1.
+func hasDeprecatedTLS(hosts sslparse.Hosts) bool {
+	return len(deprecatedTLS(hosts)) > 0
+}
+
+// expiringCerts describes every certificate that expires within days.
+// Ports without certificate data, or whose expiry date could not be
+// parsed, are skipped.
+func expiringCerts(hosts sslparse.Hosts, days int) []string {
+	var expiring []string
+	for _, host := range hosts.Hosts {
+		for _, port := range host.Ports {
+			cert := port.Certificate
+			if cert == nil || cert.NotAfter.IsZero() {
+				continue
+			}
+			if cert.DaysUntilExpiry <= days {
+				expiring = append(expiring, fmt.Sprintf("%s:%d certificate %q expires in %d days (%s)",
+					host.IP, port.ID, cert.Subject, cert.DaysUntilExpiry, cert.NotAfter.Format("2006-01-02")))
+			}
+		}
+	}
+	return expiring
+}
+
+// violations runs the policy checks enabled on the command line and
+// returns a description of every failure.
+func (cfg *config) violations(hosts sslparse.Hosts) []string {
+	var violations []string
+	if cfg.failOnDeprecated && hasDeprecatedTLS(hosts) {
+		for _, offender := range deprecatedTLS(hosts) {
+			violations = append(violations, "Deprecated TLS: "+offender)
+		}
+	}
+	if cfg.failOnPolicy {
+		for _, offender := range disallowedCiphers(hosts) {
+			violations = append(violations, "Disallowed cipher: "+offender)
+		}
+		for _, offender := range versionsBelowMin(hosts) {
+			violations = append(violations, "Below minimum TLS: "+offender)
+		}
+	}
+	if cfg.certExpiryDays > 0 {
+		for _, cert := range expiringCerts(hosts, cfg.certExpiryDays) {
+			violations = append(violations, "Expiring certificate: "+cert)
+		}
+	}
+	return violations
+}
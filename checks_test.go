@@ -0,0 +1,87 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// tlsPort returns an open TCP port that offers the given ciphers with
+// TLS 1.0 and TLS 1.2. Either list may be empty.
+func tlsPort(id uint16, tls10, tls12 []sslparse.Cipher) sslparse.Port {
+	port := sslparse.Port{ID: id, Protocol: "tcp", Service: "https", State: "open"}
+	port.TLS.TLS10 = sslparse.CipherData{Ciphers: tls10}
+	port.TLS.TLS12 = sslparse.CipherData{Ciphers: tls12}
+	return port
+}
+
+func TestDeprecatedTLS(t *testing.T) {
+	strong := []sslparse.Cipher{{Name: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", Strength: "A"}}
+	hosts := sslparse.Hosts{Hosts: []sslparse.HostInfo{
+		{IP: "10.0.0.1", Ports: []sslparse.Port{tlsPort(443, strong, strong), tlsPort(8443, nil, strong)}},
+		{IP: "10.0.0.2", Ports: []sslparse.Port{tlsPort(443, nil, strong)}},
+	}}
+
+	want := []string{"10.0.0.1:443 offers TLSv1.0"}
+	if got := deprecatedTLS(hosts); !reflect.DeepEqual(got, want) {
+		t.Errorf("deprecatedTLS = %q, want %q", got, want)
+	}
+	if !hasDeprecatedTLS(hosts) {
+		t.Error("hasDeprecatedTLS = false with TLSv1.0 offered")
+	}
+
+	hosts.Hosts = hosts.Hosts[1:]
+	if hasDeprecatedTLS(hosts) {
+		t.Error("hasDeprecatedTLS = true with only TLSv1.2 offered")
+	}
+
+	hosts.Hosts[0].Ports[0].TLS.SSL3 = sslparse.CipherData{Ciphers: strong}
+	want = []string{"10.0.0.2:443 offers SSLv3"}
+	if got := deprecatedTLS(hosts); !reflect.DeepEqual(got, want) {
+		t.Errorf("deprecatedTLS with SSLv3 = %q, want %q", got, want)
+	}
+}
+
+func TestExpiringCerts(t *testing.T) {
+	now := time.Now()
+	certPort := func(id uint16, cert *sslparse.Certificate) sslparse.Port {
+		return sslparse.Port{ID: id, Protocol: "tcp", State: "open", Certificate: cert}
+	}
+	expiry := func(days int) *sslparse.Certificate {
+		return &sslparse.Certificate{
+			Subject:         "commonName=example.com",
+			NotAfter:        now.AddDate(0, 0, days),
+			DaysUntilExpiry: days,
+		}
+	}
+	hosts := sslparse.Hosts{Hosts: []sslparse.HostInfo{{IP: "10.0.0.1", Ports: []sslparse.Port{
+		certPort(443, expiry(5)),
+		certPort(8443, expiry(90)),
+		certPort(10443, expiry(30)),
+		certPort(80, nil),
+		// An unparseable expiry date leaves NotAfter and the day count
+		// zero, which must not be taken for a certificate expiring today.
+		certPort(9443, &sslparse.Certificate{Subject: "commonName=broken", Error: "invalid date"}),
+	}}}}
+
+	got := expiringCerts(hosts, 30)
+	want := []string{
+		`10.0.0.1:443 certificate "commonName=example.com" expires in 5 days (` + now.AddDate(0, 0, 5).Format("2006-01-02") + ")",
+		// Expiring in exactly -cert-expiry-days days is within the window.
+		`10.0.0.1:10443 certificate "commonName=example.com" expires in 30 days (` + now.AddDate(0, 0, 30).Format("2006-01-02") + ")",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expiringCerts = %q, want %q", got, want)
+	}
+
+	cfg := &config{certExpiryDays: 30}
+	if v := cfg.violations(hosts); len(v) != 2 {
+		t.Errorf("violations with -cert-expiry-days 30 = %q, want 2", v)
+	}
+	cfg.certExpiryDays = 0
+	if v := cfg.violations(hosts); len(v) != 0 {
+		t.Errorf("violations without -cert-expiry-days = %q, want none", v)
+	}
+}
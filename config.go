@@ -0,0 +1,584 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"nmap-example/pkg/sslscan"
+)
+
+var (
+	defaultPorts   = sslscan.DefaultPorts
+	defaultScripts = sslscan.DefaultScripts
+
+	defaultPortStates = []string{"open"}
+)
+
+// stringList is a flag.Value that accepts both repeated flags and
+// comma-separated values, so "-ports 80,443" and "-ports 80 -ports 443"
+// are equivalent.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*s = append(*s, v)
+		}
+	}
+	return nil
+}
+
+// repeatedFlag is a flag.Value that collects every occurrence of a flag
+// verbatim, for values that may themselves contain commas.
+type repeatedFlag []string
+
+func (r *repeatedFlag) String() string {
+	return strings.Join(*r, "; ")
+}
+
+func (r *repeatedFlag) Set(value string) error {
+	*r = append(*r, value)
+	return nil
+}
+
+// config holds the options collected from the command line.
+type config struct {
+	targets     stringList
+	exclude     stringList
+	endpoints   stringList
+	portStates  stringList
+	ports       stringList
+	scripts     stringList
+	targetsFile string
+	xmlFile     string
+	resumeFile  string
+	output      string
+	format      string
+	// reports pairs each -format with its -output file, see parseReports.
+	reports []report
+	compact bool
+
+	failOnDeprecated bool
+	failOnPolicy     bool
+	serviceInfo      bool
+	onlyUp           bool
+	tcp              bool
+	udp              bool
+	quiet            bool
+	logLevel         string
+	logFormat        string
+	ipv6             bool
+	skipDiscovery    bool
+	progress         bool
+	includeRaw       bool
+	verboseJSON      bool
+	dryRun           bool
+	osDetection      bool
+	showVersion      bool
+	findingsOnly     bool
+	noDNS            bool
+	stream           bool
+	noColor          bool
+	sort             bool
+
+	timeout      time.Duration
+	hostTimeout  time.Duration
+	concurrency  int
+	batchSize    int
+	retries      int
+	hostRetries  int
+	timing       int
+	minRate      int
+	maxRate      int
+	maxHostgroup int
+
+	certExpiryDays int
+	topPorts       int
+
+	webhook        string
+	webhookHeaders repeatedFlag
+
+	sqlitePath   string
+	baselinePath string
+
+	allowedCiphersFile string
+	minTLS             string
+	// allowed is the allowlist read from allowedCiphersFile by run, or nil.
+	allowed cipherAllowlist
+	// live is the ndjson report written while nmap runs, or nil, see
+	// streamsLive.
+	live *hostStream
+
+	nmapPath  string
+	extraArgs repeatedFlag
+
+	s3URI    string
+	promFile string
+
+	iface      string
+	sourceIP   string
+	dnsServers stringList
+	fragment   bool
+	decoys     stringList
+
+	slackWebhook string
+	slackAlways  bool
+}
+
+// parseFlags parses the command line arguments, without the program name,
+// into a config.
+func parseFlags(args []string) (*config, error) {
+	cfg := &config{}
+	flag.Var(&cfg.targets, "targets", "comma-separated list of hostnames, IPs or CIDR ranges to scan (repeatable)")
+	flag.Var(&cfg.ports, "ports", "comma-separated list of ports to scan (repeatable, default 443,80)")
+	flag.Var(&cfg.scripts, "scripts", "comma-separated list of NSE scripts to run (repeatable, default ssl-enum-ciphers)")
+	flag.StringVar(&cfg.output, "output", "", "write the report to this file instead of stdout; with several -format values, a comma-separated file per format")
+	flag.StringVar(&cfg.output, "o", "", "shorthand for -output")
+	flag.StringVar(&cfg.format, "format", "json", "comma-separated report formats: "+strings.Join(formats, ", "))
+	flag.BoolVar(&cfg.stream, "stream", false, "write the json report host by host instead of holding it all in memory; ndjson is written host by host whenever nothing needs the complete report")
+	flag.BoolVar(&cfg.noColor, "no-color", false, "never color the table format, even on a terminal")
+	flag.BoolVar(&cfg.sort, "sort", false, "sort hosts by IP, ports by number and ciphers by name, for reports that can be diffed")
+	flag.BoolVar(&cfg.compact, "compact", false, "write the json format without indentation")
+	flag.Var(&cfg.endpoints, "endpoints", "comma-separated host:port pairs, each host scanned on only its own ports (repeatable)")
+	flag.Var(&cfg.exclude, "exclude", "comma-separated list of IPs, CIDR ranges or hostnames to skip (repeatable)")
+	flag.StringVar(&cfg.targetsFile, "targets-file", "", "file with one target per line; blank lines and # comments are ignored")
+	flag.BoolVar(&cfg.failOnDeprecated, "fail-on-deprecated", false, "exit with code 2 if any port offers SSLv3, TLS 1.0 or 1.1")
+	flag.StringVar(&cfg.allowedCiphersFile, "allowed-ciphers-file", "", "file with one approved cipher per line; other offered ciphers are reported as disallowed")
+	flag.StringVar(&cfg.minTLS, "min-tls", "", "lowest acceptable TLS version, e.g. 1.2; ports offering an older version are reported")
+	flag.BoolVar(&cfg.failOnPolicy, "fail-on-policy", false, "exit with code 2 if any port offers a cipher missing from -allowed-ciphers-file or a version below -min-tls")
+	flag.BoolVar(&cfg.serviceInfo, "sV", false, "probe open ports to determine service product and version")
+	flag.BoolVar(&cfg.osDetection, "O", false, "enable OS detection and report the best match per host (requires root)")
+	flag.Var(&cfg.portStates, "port-state", "comma-separated port states to report, e.g. open,filtered (repeatable, default open)")
+	flag.BoolVar(&cfg.findingsOnly, "findings-only", false, "only report ports with weak ciphers, deprecated TLS, disallowed ciphers or versions below -min-tls")
+	flag.BoolVar(&cfg.onlyUp, "only-up", false, "only report hosts that are up")
+	flag.DurationVar(&cfg.timeout, "timeout", 5*time.Minute, "overall scan timeout, e.g. 90s or 10m; 0 disables the timeout")
+	flag.DurationVar(&cfg.hostTimeout, "host-timeout", 0, "give up on a single host after this long, e.g. 2m (nmap --host-timeout); must be below -timeout")
+	flag.BoolVar(&cfg.tcp, "tcp", true, "scan TCP ports; -udp turns this off unless -tcp is given too")
+	flag.BoolVar(&cfg.udp, "udp", false, "scan UDP ports, and TCP ports only if -tcp is given (requires root)")
+	flag.BoolVar(&cfg.quiet, "quiet", false, "only log errors; shorthand for -log-level error")
+	flag.StringVar(&cfg.logLevel, "log-level", "info", "minimum log level: debug, info, warn or error")
+	flag.StringVar(&cfg.logFormat, "log-format", "text", "log format: text or json")
+	flag.StringVar(&cfg.resumeFile, "resume", "", "resume the interrupted nmap scan recorded in this -oN or -oG output file, then exit")
+	flag.StringVar(&cfg.xmlFile, "xml", "", "parse a previous nmap XML scan instead of running nmap")
+	flag.IntVar(&cfg.concurrency, "concurrency", 1, "number of target batches to scan in parallel")
+	flag.IntVar(&cfg.batchSize, "batch-size", 0, "split targets into batches of this many entries; 0 scans all targets at once")
+	flag.BoolVar(&cfg.ipv6, "6", false, "scan IPv6 addresses")
+	flag.IntVar(&cfg.retries, "retries", 0, "number of times to retry a failed nmap run, with exponential backoff")
+	flag.IntVar(&cfg.hostRetries, "host-retries", -1, "maximum number of probe retransmissions per port (nmap --max-retries); unset uses nmap's default")
+	flag.BoolVar(&cfg.noDNS, "no-dns", false, "never do reverse DNS resolution (nmap -n); hostnames will usually be empty")
+	flag.StringVar(&cfg.iface, "interface", "", "send packets through this network interface (nmap -e)")
+	flag.StringVar(&cfg.sourceIP, "source-ip", "", "use this source address for probes (nmap -S)")
+	flag.BoolVar(&cfg.fragment, "fragment", false, "split probes into tiny IP fragments (nmap -f; requires root)")
+	flag.Var(&cfg.decoys, "decoys", "comma-separated decoy IPs to scan from alongside the real address, which ME places in the list (nmap -D; repeatable)")
+	flag.Var(&cfg.dnsServers, "dns-servers", "comma-separated IPs of the DNS servers nmap should query instead of the system resolver (repeatable)")
+	flag.BoolVar(&cfg.skipDiscovery, "skip-discovery", false, "treat all targets as up and skip host discovery (nmap -Pn)")
+	flag.IntVar(&cfg.timing, "timing", -1, "nmap timing template from 0 (paranoid) to 5 (insane); unset uses nmap's default")
+	flag.IntVar(&cfg.minRate, "min-rate", 0, "send at least this many packets per second; 0 uses nmap's default")
+	flag.IntVar(&cfg.maxRate, "max-rate", 0, "send at most this many packets per second; 0 uses nmap's default")
+	flag.IntVar(&cfg.maxHostgroup, "max-hostgroup", 0, "scan at most this many hosts in parallel within nmap (nmap --max-hostgroup); unset uses nmap's default")
+	flag.IntVar(&cfg.certExpiryDays, "cert-expiry-days", 0, "exit with code 2 if any certificate expires within this many days (needs the ssl-cert script)")
+	flag.IntVar(&cfg.topPorts, "top-ports", 0, "scan the N most common ports instead of -ports")
+	flag.StringVar(&cfg.webhook, "webhook", "", "POST the JSON report to this URL")
+	flag.Var(&cfg.webhookHeaders, "webhook-header", `extra "Name: value" header for -webhook requests (repeatable)`)
+	flag.StringVar(&cfg.sqlitePath, "sqlite", "", "also store the results in this SQLite database for historical tracking")
+	flag.StringVar(&cfg.baselinePath, "baseline", "", "compare the results against this previous JSON report and log the changes")
+	flag.BoolVar(&cfg.includeRaw, "include-raw", false, "include the untouched output of every script in the report")
+	flag.BoolVar(&cfg.verboseJSON, "verbose-json", false, "include the raw ssl-enum-ciphers output next to the parsed data of every port")
+	flag.BoolVar(&cfg.dryRun, "dry-run", false, "print the nmap command line for every batch and exit without scanning")
+	flag.StringVar(&cfg.nmapPath, "nmap-path", "", "path to the nmap binary; defaults to looking it up on PATH")
+	flag.Var(&cfg.extraArgs, "extra-args", "whitespace-separated arguments passed to nmap as is (repeatable)")
+	flag.BoolVar(&cfg.showVersion, "version", false, "print the version and build information and exit")
+	flag.StringVar(&cfg.promFile, "prom-file", "", "also write Prometheus metrics to this file for the node_exporter textfile collector")
+	flag.StringVar(&cfg.s3URI, "s3", "", "also upload the JSON report to this s3://bucket/key location")
+	flag.StringVar(&cfg.slackWebhook, "slack-webhook", "", "post a summary to this Slack incoming webhook URL when a port has weak ciphers, deprecated TLS, disallowed ciphers or versions below -min-tls")
+	flag.BoolVar(&cfg.slackAlways, "slack-always", false, "also post to -slack-webhook when the scan is clean")
+	flag.BoolVar(&cfg.progress, "progress", false, "log scan progress and an ETA while nmap runs")
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return nil, err
+	}
+	if cfg.showVersion {
+		return cfg, nil
+	}
+
+	if cfg.quiet {
+		cfg.logLevel = "error"
+	}
+	logger, err := newLogger(os.Stderr, cfg.logLevel, cfg.logFormat)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	if cfg.timeout < 0 {
+		return nil, fmt.Errorf("invalid -timeout %s: must not be negative", cfg.timeout)
+	}
+	if cfg.hostTimeout < 0 {
+		return nil, fmt.Errorf("invalid -host-timeout %s: must not be negative", cfg.hostTimeout)
+	}
+	// nmap takes the host timeout in milliseconds.
+	if cfg.hostTimeout > 0 && cfg.hostTimeout < time.Millisecond {
+		return nil, fmt.Errorf("invalid -host-timeout %s: must be at least 1ms", cfg.hostTimeout)
+	}
+	if cfg.hostTimeout > 0 && cfg.timeout > 0 && cfg.hostTimeout >= cfg.timeout {
+		return nil, fmt.Errorf("invalid -host-timeout %s: must be below -timeout %s", cfg.hostTimeout, cfg.timeout)
+	}
+	// -udp on its own asks for a UDP scan, so TCP is only kept when -tcp
+	// was given explicitly.
+	if cfg.udp && !isFlagSet("tcp") {
+		cfg.tcp = false
+	}
+	if !cfg.tcp && !cfg.udp {
+		return nil, errors.New("-tcp=false requires -udp")
+	}
+	if cfg.concurrency < 1 {
+		return nil, fmt.Errorf("invalid -concurrency %d: must be at least 1", cfg.concurrency)
+	}
+	if cfg.batchSize < 0 {
+		return nil, fmt.Errorf("invalid -batch-size %d: must not be negative", cfg.batchSize)
+	}
+	if cfg.retries < 0 || cfg.retries > maxRetries {
+		return nil, fmt.Errorf("invalid -retries %d: must be between 0 and %d", cfg.retries, maxRetries)
+	}
+	if cfg.hostRetries < -1 {
+		return nil, fmt.Errorf("invalid -host-retries %d: must not be negative", cfg.hostRetries)
+	}
+	if err := validateTiming(cfg.timing); err != nil {
+		return nil, err
+	}
+	if isFlagSet("interface") && (cfg.iface == "" || strings.ContainsAny(cfg.iface, " \t")) {
+		return nil, fmt.Errorf("invalid -interface %q: must be a non-empty interface name", cfg.iface)
+	}
+	if cfg.sourceIP != "" && net.ParseIP(cfg.sourceIP) == nil {
+		return nil, fmt.Errorf("invalid -source-ip %q: not an IP address", cfg.sourceIP)
+	}
+	for _, decoy := range cfg.decoys {
+		if !strings.EqualFold(decoy, "ME") && net.ParseIP(decoy) == nil {
+			return nil, fmt.Errorf("invalid -decoys entry %q: not an IP address or ME", decoy)
+		}
+	}
+	for _, server := range cfg.dnsServers {
+		if net.ParseIP(server) == nil {
+			return nil, fmt.Errorf("invalid -dns-servers entry %q: not an IP address", server)
+		}
+	}
+	if len(cfg.dnsServers) > 0 && cfg.noDNS {
+		return nil, errors.New("-dns-servers cannot be combined with -no-dns, which disables DNS resolution")
+	}
+	if err := validateRates(cfg.minRate, cfg.maxRate); err != nil {
+		return nil, err
+	}
+	if isFlagSet("max-hostgroup") && cfg.maxHostgroup < 1 {
+		return nil, fmt.Errorf("invalid -max-hostgroup %d: must be positive", cfg.maxHostgroup)
+	}
+	if cfg.certExpiryDays < 0 {
+		return nil, fmt.Errorf("invalid -cert-expiry-days %d: must not be negative", cfg.certExpiryDays)
+	}
+	for _, h := range cfg.webhookHeaders {
+		if name, _, found := strings.Cut(h, ":"); !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf(`invalid -webhook-header %q: want "Name: value"`, h)
+		}
+	}
+	if cfg.resumeFile != "" {
+		if cfg.xmlFile != "" || cfg.dryRun {
+			return nil, errors.New("-resume cannot be combined with -xml or -dry-run")
+		}
+		if _, err := os.Stat(cfg.resumeFile); err != nil {
+			return nil, fmt.Errorf("invalid -resume: %w", err)
+		}
+	}
+	if cfg.dryRun && cfg.xmlFile != "" {
+		return nil, errors.New("-dry-run cannot be combined with -xml")
+	}
+	if cfg.nmapPath != "" {
+		if err := validateBinary(cfg.nmapPath); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.s3URI != "" {
+		if _, _, err := parseS3URI(cfg.s3URI); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.slackAlways && cfg.slackWebhook == "" {
+		return nil, errors.New("-slack-always requires -slack-webhook")
+	}
+	reports, err := parseReports(cfg.format, cfg.output)
+	if err != nil {
+		return nil, err
+	}
+	cfg.reports = reports
+	if cfg.stream {
+		if err := cfg.validateStream(); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.minTLS != "" {
+		name, err := parseMinTLS(cfg.minTLS)
+		if err != nil {
+			return nil, err
+		}
+		cfg.minTLS = name
+	}
+	if cfg.failOnPolicy && cfg.allowedCiphersFile == "" && cfg.minTLS == "" {
+		return nil, errors.New("-fail-on-policy requires -allowed-ciphers-file or -min-tls")
+	}
+
+	if cfg.targetsFile != "" {
+		fileTargets, err := readTargetsFromFile(cfg.targetsFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.targets = append(cfg.targets, fileTargets...)
+	}
+	targets, err := normalizeTargets(cfg.targets)
+	if err != nil {
+		return nil, err
+	}
+	targets, covered := dropCovered(targets)
+	for _, c := range covered {
+		slog.Info("skipping target already covered by a range", "target", c.target, "range", c.by)
+	}
+	cfg.targets = targets
+
+	if err := validatePortStates(cfg.portStates); err != nil {
+		return nil, err
+	}
+	if err := validateExclusions(cfg.exclude); err != nil {
+		return nil, err
+	}
+
+	if err := validateEndpoints(cfg.endpoints); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.targets) == 0 && len(cfg.endpoints) == 0 && cfg.xmlFile == "" && cfg.resumeFile == "" {
+		flag.Usage()
+		return nil, errors.New("at least one target is required")
+	}
+	if cfg.topPorts < 0 {
+		return nil, fmt.Errorf("invalid -top-ports %d: must be positive", cfg.topPorts)
+	}
+	if err := validatePorts(cfg.ports); err != nil {
+		return nil, err
+	}
+	if cfg.topPorts > 0 && len(cfg.ports) > 0 {
+		return nil, errors.New("-top-ports and -ports are mutually exclusive")
+	}
+	if len(cfg.ports) == 0 && cfg.topPorts == 0 {
+		cfg.ports = defaultPorts
+	}
+	if len(cfg.scripts) == 0 {
+		cfg.scripts = defaultScripts
+	}
+	if len(cfg.portStates) == 0 {
+		cfg.portStates = defaultPortStates
+	}
+	return cfg, nil
+}
+
+// applyEnv fills in settings from the environment for container runs where
+// flags are awkward to pass. The precedence is, from highest to lowest:
+//
+//  1. flags given on the command line;
+//  2. the NMAP_TARGETS, NMAP_PORTS, NMAP_SCRIPTS and NMAP_TIMEOUT
+//     environment variables, using the same syntax as the flags;
+//  3. the built-in defaults.
+//
+// It must be called after the flags are parsed, since flag.Visit only
+// reports the flags that were actually set.
+func (cfg *config) applyEnv(lookup func(string) (string, bool)) error {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	// -top-ports replaces -ports, so it also overrides NMAP_PORTS.
+	if set["top-ports"] {
+		set["ports"] = true
+	}
+
+	for name, list := range map[string]*stringList{
+		"targets": &cfg.targets,
+		"ports":   &cfg.ports,
+		"scripts": &cfg.scripts,
+	} {
+		if value, ok := lookup("NMAP_" + strings.ToUpper(name)); ok && !set[name] {
+			list.Set(value)
+		}
+	}
+	if value, ok := lookup("NMAP_TIMEOUT"); ok && !set["timeout"] {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid NMAP_TIMEOUT %q: %w", value, err)
+		}
+		cfg.timeout = timeout
+	}
+	return nil
+}
+
+// reportOptions returns the settings for rendering the report. Colors are
+// only used when the report goes to a terminal.
+func (cfg *config) reportOptions() reportOptions {
+	return reportOptions{
+		compact: cfg.compact,
+		color:   !cfg.noColor && cfg.output == "" && isTerminal(os.Stdout),
+	}
+}
+
+// validateStream rejects -stream with formats that cannot be written host
+// by host, and with features that need the complete report.
+func (cfg *config) validateStream() error {
+	if len(cfg.reports) > 1 {
+		return errors.New("-stream writes a single report, give only one -format")
+	}
+	streamable := false
+	for _, f := range streamFormats {
+		streamable = streamable || f == cfg.format
+	}
+	if !streamable {
+		return fmt.Errorf("-stream only supports the %s formats", strings.Join(streamFormats, " and "))
+	}
+	if flag := cfg.needsReport(); flag != "" {
+		return fmt.Errorf("-stream cannot be combined with %s, which needs the complete report", flag)
+	}
+	return nil
+}
+
+// needsReport returns the first flag in use that needs the complete
+// report, which rules out writing it host by host, or "" if there is none.
+func (cfg *config) needsReport() string {
+	needsReport := []struct {
+		flag string
+		used bool
+	}{
+		{"-baseline", cfg.baselinePath != ""},
+		{"-sqlite", cfg.sqlitePath != ""},
+		{"-webhook", cfg.webhook != ""},
+		{"-slack-webhook", cfg.slackWebhook != ""},
+		{"-s3", cfg.s3URI != ""},
+		{"-prom-file", cfg.promFile != ""},
+		{"-fail-on-deprecated", cfg.failOnDeprecated},
+		{"-fail-on-policy", cfg.failOnPolicy},
+		{"-cert-expiry-days", cfg.certExpiryDays > 0},
+		{"-sort", cfg.sort},
+	}
+	for _, f := range needsReport {
+		if f.used {
+			return f.flag
+		}
+	}
+	return ""
+}
+
+// isFlagSet reports whether the named flag was given on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// validateBinary checks that path is an executable file.
+func validateBinary(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("invalid -nmap-path: %w", err)
+	}
+	if info.IsDir() || info.Mode()&0o111 == 0 {
+		return fmt.Errorf("invalid -nmap-path %s: not an executable file", path)
+	}
+	return nil
+}
+
+// validatePorts checks -ports entries, each a single port such as 443 or
+// an inclusive range such as 1-1000 whose start or end may be left out as
+// in nmap's -p syntax (-1024, 1024- or just -). An entry may be wrapped in
+// brackets to limit it to the ports in nmap-services, and prefixed with a
+// protocol qualifier (T:, U:, S: or P:). Service names such as https are
+// rejected as non-numeric. Comma lists are already split by stringList.
+func validatePorts(ports []string) error {
+	for _, entry := range ports {
+		spec := entry
+		if len(spec) > 2 && spec[1] == ':' && strings.ContainsRune("TUSP", rune(spec[0])) {
+			spec = spec[2:]
+		}
+		if len(spec) > 2 && spec[0] == '[' && spec[len(spec)-1] == ']' {
+			spec = spec[1 : len(spec)-1]
+		}
+		low, high, isRange := strings.Cut(spec, "-")
+		if isRange && low == "" && high == "" {
+			continue
+		}
+		first, last := 0, 65535
+		var err error
+		if low != "" || !isRange {
+			if first, err = parsePort(low); err != nil {
+				return fmt.Errorf("invalid -ports entry %q: %w", entry, err)
+			}
+		}
+		if !isRange {
+			continue
+		}
+		if high != "" {
+			if last, err = parsePort(high); err != nil {
+				return fmt.Errorf("invalid -ports entry %q: %w", entry, err)
+			}
+		}
+		if first > last {
+			return fmt.Errorf("invalid -ports entry %q: range start is greater than its end", entry)
+		}
+	}
+	return nil
+}
+
+// parsePort parses a single port number between 0 and 65535.
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || strings.Trim(s, "0123456789") != "" {
+		return 0, fmt.Errorf("%q is not a port number", s)
+	}
+	if port < 0 || port > 65535 {
+		return 0, fmt.Errorf("port %d is out of range 0-65535", port)
+	}
+	return port, nil
+}
+
+// validateRates checks the -min-rate and -max-rate packet rates, where
+// zero means the flag was not set.
+func validateRates(minRate, maxRate int) error {
+	if minRate < 0 {
+		return fmt.Errorf("invalid -min-rate %d: must be positive", minRate)
+	}
+	if maxRate < 0 {
+		return fmt.Errorf("invalid -max-rate %d: must be positive", maxRate)
+	}
+	if minRate > 0 && maxRate > 0 && minRate > maxRate {
+		return fmt.Errorf("invalid -min-rate %d: must not exceed -max-rate %d", minRate, maxRate)
+	}
+	return nil
+}
+
+// validateTiming accepts the nmap timing templates -T0 to -T5, or -1 when
+// the flag was not set.
+func validateTiming(timing int) error {
+	if timing < -1 || timing > 5 {
+		return fmt.Errorf("invalid -timing %d: must be between 0 and 5", timing)
+	}
+	return nil
+}
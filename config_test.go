@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidateTiming(t *testing.T) {
+	for _, timing := range []int{-1, 0, 3, 5} {
+		if err := validateTiming(timing); err != nil {
+			t.Errorf("validateTiming(%d) = %v, want nil", timing, err)
+		}
+	}
+	for _, timing := range []int{-2, 6, 100} {
+		if err := validateTiming(timing); err == nil {
+			t.Errorf("validateTiming(%d) = nil, want an error", timing)
+		}
+	}
+}
+
+func TestValidateBinary(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "nmap.txt")
+	if err := os.WriteFile(plain, []byte("not a program"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := validateBinary("testdata/fake-nmap"); err != nil {
+		t.Errorf("executable: %v", err)
+	}
+	for _, path := range []string{plain, dir, filepath.Join(dir, "missing")} {
+		if err := validateBinary(path); err == nil {
+			t.Errorf("validateBinary(%s) = nil, want an error", path)
+		}
+	}
+}
+
+func TestValidateRates(t *testing.T) {
+	tests := []struct {
+		min, max int
+		ok       bool
+	}{
+		{0, 0, true},
+		{100, 0, true},
+		{0, 100, true},
+		{100, 100, true},
+		{100, 1000, true},
+		{1000, 100, false},
+		{-1, 0, false},
+		{0, -1, false},
+	}
+	for _, tt := range tests {
+		if err := validateRates(tt.min, tt.max); (err == nil) != tt.ok {
+			t.Errorf("validateRates(%d, %d) = %v, want ok %v", tt.min, tt.max, err, tt.ok)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"NMAP_TARGETS": "a.example.com, b.example.com",
+		"NMAP_PORTS":   "8443",
+		"NMAP_SCRIPTS": "ssl-cert",
+		"NMAP_TIMEOUT": "90s",
+	}
+	lookup := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+	// parse registers the flags applyEnv looks at and parses args.
+	parse := func(args ...string) *config {
+		t.Helper()
+		resetFlags(t)
+		cfg := &config{}
+		flag.Var(&cfg.targets, "targets", "")
+		flag.Var(&cfg.ports, "ports", "")
+		flag.Var(&cfg.scripts, "scripts", "")
+		flag.DurationVar(&cfg.timeout, "timeout", 5*time.Minute, "")
+		flag.IntVar(&cfg.topPorts, "top-ports", 0, "")
+		if err := flag.CommandLine.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		if err := cfg.applyEnv(lookup); err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+
+	cfg := parse()
+	if want := (stringList{"a.example.com", "b.example.com"}); !reflect.DeepEqual(cfg.targets, want) {
+		t.Errorf("targets = %q, want %q from the environment", cfg.targets, want)
+	}
+	if !reflect.DeepEqual(cfg.ports, stringList{"8443"}) || !reflect.DeepEqual(cfg.scripts, stringList{"ssl-cert"}) {
+		t.Errorf("ports, scripts = %q, %q, want the environment values", cfg.ports, cfg.scripts)
+	}
+	if cfg.timeout != 90*time.Second {
+		t.Errorf("timeout = %s, want 90s", cfg.timeout)
+	}
+
+	// Flags take precedence, and -top-ports overrides NMAP_PORTS.
+	cfg = parse("-targets", "c.example.com", "-timeout", "10s", "-top-ports", "20")
+	if !reflect.DeepEqual(cfg.targets, stringList{"c.example.com"}) || cfg.timeout != 10*time.Second {
+		t.Errorf("targets, timeout = %q, %s, want the flag values", cfg.targets, cfg.timeout)
+	}
+	if cfg.ports != nil {
+		t.Errorf("ports = %q with -top-ports, want none", cfg.ports)
+	}
+
+	env["NMAP_TIMEOUT"] = "soon"
+	resetFlags(t)
+	if err := (&config{}).applyEnv(lookup); err == nil {
+		t.Error("invalid NMAP_TIMEOUT: got no error")
+	}
+}
+
+func TestValidatePorts(t *testing.T) {
+	valid := [][]string{
+		{"443"}, {"0", "65535"}, {"1-1024"}, {"T:443", "U:53"}, {"8000-8000"},
+		// Open ranges and nmap-services brackets.
+		{"-1024"}, {"1024-"}, {"-"}, {"P:-1"}, {"[1-1024]"},
+	}
+	for _, ports := range valid {
+		if err := validatePorts(ports); err != nil {
+			t.Errorf("validatePorts(%q) = %v, want nil", ports, err)
+		}
+	}
+	invalid := [][]string{
+		{"65536"}, {"abc"}, {"https"}, {"443-80"}, {"+443"}, {"X:443"}, {""},
+		{"-65536"}, {"1-2-3"}, {"[443"}, {"http*"}, {"T:ssh"}, {"[https]"},
+	}
+	for _, ports := range invalid {
+		if err := validatePorts(ports); err == nil {
+			t.Errorf("validatePorts(%q) = nil, want an error", ports)
+		}
+	}
+}
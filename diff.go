@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// Diff lists the changes between a baseline report and a new one. Hosts
+// without changes are left out.
+type Diff struct {
+	Hosts []HostDiff `json:"hosts"`
+}
+
+// HostDiff is the set of changes for a single host, identified by IP.
+type HostDiff struct {
+	IP string `json:"ip"`
+	// Added and Removed are set when the host only appears in the new or
+	// the baseline report respectively.
+	Added        bool       `json:"added,omitempty"`
+	Removed      bool       `json:"removed,omitempty"`
+	AddedPorts   []string   `json:"added_ports,omitempty"`
+	RemovedPorts []string   `json:"removed_ports,omitempty"`
+	Ports        []PortDiff `json:"ports,omitempty"`
+}
+
+// PortDiff holds the TLS changes on a port present in both reports. Ports
+// are written as "443/tcp" and ciphers as "TLSv1.2 NAME".
+type PortDiff struct {
+	Port            string   `json:"port"`
+	AddedVersions   []string `json:"added_versions,omitempty"`
+	RemovedVersions []string `json:"removed_versions,omitempty"`
+	AddedCiphers    []string `json:"added_ciphers,omitempty"`
+	RemovedCiphers  []string `json:"removed_ciphers,omitempty"`
+}
+
+// Empty reports whether there are no changes at all.
+func (d Diff) Empty() bool {
+	return len(d.Hosts) == 0
+}
+
+// readBaseline loads a JSON report written by a previous run. Decoding is
+// strict: unknown fields, trailing data or a host without an IP mean the
+// file is not a report in the json format, and diffing it would only give
+// confusing results.
+func readBaseline(path string) (sslparse.Hosts, error) {
+	var hosts sslparse.Hosts
+	f, err := os.Open(path)
+	if err != nil {
+		return hosts, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&hosts); err != nil {
+		return hosts, fmt.Errorf("invalid baseline %s: not a json report: %w", path, err)
+	}
+	if dec.More() {
+		return hosts, fmt.Errorf("invalid baseline %s: unexpected data after the report", path)
+	}
+	for i, host := range hosts.Hosts {
+		if host.IP == "" {
+			return hosts, fmt.Errorf("invalid baseline %s: host %d has no ip", path, i)
+		}
+	}
+	return hosts, nil
+}
+
+// diffHosts compares two reports. Hosts are listed in the order of the new
+// report, followed by the hosts that disappeared since the baseline.
+func diffHosts(old, new sslparse.Hosts) Diff {
+	oldHosts := make(map[string]sslparse.HostInfo, len(old.Hosts))
+	for _, host := range old.Hosts {
+		oldHosts[host.IP] = host
+	}
+	newHosts := make(map[string]bool, len(new.Hosts))
+
+	diff := Diff{Hosts: []HostDiff{}}
+	for _, host := range new.Hosts {
+		newHosts[host.IP] = true
+		prev, ok := oldHosts[host.IP]
+		if !ok {
+			diff.Hosts = append(diff.Hosts, HostDiff{IP: host.IP, Added: true, AddedPorts: portKeys(host.Ports)})
+			continue
+		}
+		if hd := diffHost(prev, host); !hd.empty() {
+			diff.Hosts = append(diff.Hosts, hd)
+		}
+	}
+	for _, host := range old.Hosts {
+		if !newHosts[host.IP] {
+			diff.Hosts = append(diff.Hosts, HostDiff{IP: host.IP, Removed: true, RemovedPorts: portKeys(host.Ports)})
+		}
+	}
+	return diff
+}
+
+func diffHost(old, new sslparse.HostInfo) HostDiff {
+	hd := HostDiff{IP: new.IP}
+	oldPorts := make(map[string]sslparse.Port, len(old.Ports))
+	for _, port := range old.Ports {
+		oldPorts[portKey(port)] = port
+	}
+	newPorts := make(map[string]bool, len(new.Ports))
+
+	for _, port := range new.Ports {
+		key := portKey(port)
+		newPorts[key] = true
+		prev, ok := oldPorts[key]
+		if !ok {
+			hd.AddedPorts = append(hd.AddedPorts, key)
+			continue
+		}
+		pd := PortDiff{Port: key}
+		pd.AddedVersions, pd.RemovedVersions = setDiff(prev.TLS.Offered(), port.TLS.Offered())
+		pd.AddedCiphers, pd.RemovedCiphers = setDiff(offeredCiphers(prev), offeredCiphers(port))
+		if !pd.empty() {
+			hd.Ports = append(hd.Ports, pd)
+		}
+	}
+	for _, port := range old.Ports {
+		if key := portKey(port); !newPorts[key] {
+			hd.RemovedPorts = append(hd.RemovedPorts, key)
+		}
+	}
+	return hd
+}
+
+func (hd HostDiff) empty() bool {
+	return !hd.Added && !hd.Removed && len(hd.AddedPorts) == 0 && len(hd.RemovedPorts) == 0 && len(hd.Ports) == 0
+}
+
+func (pd PortDiff) empty() bool {
+	return len(pd.AddedVersions) == 0 && len(pd.RemovedVersions) == 0 &&
+		len(pd.AddedCiphers) == 0 && len(pd.RemovedCiphers) == 0
+}
+
+// Changes describes every change on its own line, for logging.
+func (d Diff) Changes() []string {
+	var changes []string
+	for _, hd := range d.Hosts {
+		switch {
+		case hd.Added:
+			changes = append(changes, fmt.Sprintf("%s: new host with ports %s", hd.IP, joinOrNone(hd.AddedPorts)))
+			continue
+		case hd.Removed:
+			changes = append(changes, fmt.Sprintf("%s: host no longer found (had ports %s)", hd.IP, joinOrNone(hd.RemovedPorts)))
+			continue
+		}
+		for _, port := range hd.AddedPorts {
+			changes = append(changes, fmt.Sprintf("%s: port %s added", hd.IP, port))
+		}
+		for _, port := range hd.RemovedPorts {
+			changes = append(changes, fmt.Sprintf("%s: port %s removed", hd.IP, port))
+		}
+		for _, pd := range hd.Ports {
+			prefix := hd.IP + " " + pd.Port
+			for _, v := range pd.AddedVersions {
+				changes = append(changes, fmt.Sprintf("%s: now offers %s", prefix, v))
+			}
+			for _, v := range pd.RemovedVersions {
+				changes = append(changes, fmt.Sprintf("%s: no longer offers %s", prefix, v))
+			}
+			for _, c := range pd.AddedCiphers {
+				changes = append(changes, fmt.Sprintf("%s: cipher %s added", prefix, c))
+			}
+			for _, c := range pd.RemovedCiphers {
+				changes = append(changes, fmt.Sprintf("%s: cipher %s removed", prefix, c))
+			}
+		}
+	}
+	return changes
+}
+
+func portKey(port sslparse.Port) string {
+	return fmt.Sprintf("%d/%s", port.ID, port.Protocol)
+}
+
+func portKeys(ports []sslparse.Port) []string {
+	var keys []string
+	for _, port := range ports {
+		keys = append(keys, portKey(port))
+	}
+	return keys
+}
+
+// offeredCiphers lists every cipher on port as "VERSION NAME".
+func offeredCiphers(port sslparse.Port) []string {
+	var ciphers []string
+	for _, v := range port.TLS.Versions() {
+		for _, cipher := range v.Data.Ciphers {
+			ciphers = append(ciphers, v.Name+" "+cipher.Name)
+		}
+	}
+	return ciphers
+}
+
+// setDiff returns the entries of new missing from old, and those of old
+// missing from new, each in their original order.
+func setDiff(old, new []string) (added, removed []string) {
+	inOld := make(map[string]bool, len(old))
+	for _, s := range old {
+		inOld[s] = true
+	}
+	inNew := make(map[string]bool, len(new))
+	for _, s := range new {
+		inNew[s] = true
+		if !inOld[s] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range old {
+		if !inNew[s] {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
+
+func joinOrNone(s []string) string {
+	if len(s) == 0 {
+		return "none"
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// ciphers returns cipher data offering the named ciphers.
+func ciphers(names ...string) sslparse.CipherData {
+	var data sslparse.CipherData
+	for _, name := range names {
+		data.Ciphers = append(data.Ciphers, sslparse.Cipher{Name: name})
+	}
+	return data
+}
+
+func TestDiffHosts(t *testing.T) {
+	const (
+		gcm = "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"
+		cbc = "TLS_RSA_WITH_AES_128_CBC_SHA"
+		aes = "TLS_AKE_WITH_AES_128_GCM_SHA256"
+	)
+	port := func(id uint16, tls sslparse.TLSVersions) sslparse.Port {
+		return sslparse.Port{ID: id, Protocol: "tcp", State: "open", TLS: tls}
+	}
+	host := func(ports ...sslparse.Port) sslparse.Hosts {
+		return sslparse.Hosts{Hosts: []sslparse.HostInfo{{IP: "10.0.0.1", Ports: ports}}}
+	}
+	baseline := host(port(443, sslparse.TLSVersions{TLS12: ciphers(gcm, cbc), TLS13: ciphers(aes)}))
+
+	tests := []struct {
+		name string
+		new  sslparse.Hosts
+		want []string
+	}{
+		{
+			name: "unchanged",
+			new:  baseline,
+		},
+		{
+			name: "added port",
+			new: host(
+				port(443, sslparse.TLSVersions{TLS12: ciphers(gcm, cbc), TLS13: ciphers(aes)}),
+				port(8443, sslparse.TLSVersions{}),
+			),
+			want: []string{"10.0.0.1: port 8443/tcp added"},
+		},
+		{
+			name: "removed cipher",
+			new:  host(port(443, sslparse.TLSVersions{TLS12: ciphers(gcm), TLS13: ciphers(aes)})),
+			want: []string{"10.0.0.1 443/tcp: cipher TLSv1.2 " + cbc + " removed"},
+		},
+		{
+			name: "downgraded version",
+			new:  host(port(443, sslparse.TLSVersions{TLS10: ciphers(cbc), TLS12: ciphers(gcm, cbc)})),
+			want: []string{
+				"10.0.0.1 443/tcp: now offers TLSv1.0",
+				"10.0.0.1 443/tcp: no longer offers TLSv1.3",
+				"10.0.0.1 443/tcp: cipher TLSv1.0 " + cbc + " added",
+				"10.0.0.1 443/tcp: cipher TLSv1.3 " + aes + " removed",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := diffHosts(baseline, tt.new)
+			if got := diff.Changes(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changes = %q, want %q", got, tt.want)
+			}
+			if diff.Empty() != (tt.want == nil) {
+				t.Errorf("Empty() = %v with changes %q", diff.Empty(), tt.want)
+			}
+		})
+	}
+}
+
+func TestReadBaseline(t *testing.T) {
+	dir := t.TempDir()
+	report, err := json.Marshal(testHosts(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := filepath.Join(dir, "baseline.json")
+	if err := os.WriteFile(valid, report, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	hosts, err := readBaseline(valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hostIPs(hosts), []string{"93.184.216.34", "10.0.0.2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("hosts = %q, want %q", got, want)
+	}
+
+	for name, data := range map[string]string{
+		"truncated":     `{"hosts":[{"ip":"10.0.0.1"`,
+		"unknown field": `{"hosts":[],"nmaprun":{}}`,
+		// Unknown keys are caught inside each TLS version too.
+		"unknown TLS field": `{"hosts":[{"ip":"10.0.0.1","ports":[{"id":443,"ssl-enum-ciphers":{"TLSv1.2":{"ciphrs":[]}}}]}]}`,
+		"trailing data":     `{"hosts":[]} {"hosts":[]}`,
+		"host without ip":   `{"hosts":[{"status":"up"}]}`,
+		"not an object":     `[1, 2, 3]`,
+	} {
+		path := filepath.Join(dir, "bad.json")
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readBaseline(path); err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+	}
+	if _, err := readBaseline(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("missing file: got no error")
+	}
+}
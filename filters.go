@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// filter applies the command line filters, the cipher allowlist and the
+// -min-tls policy, if any, to hosts. The summary computed by ParseRun
+// counts the unfiltered hosts, so it is recomputed, but before
+// -findings-only so that it still describes the whole scan.
+func (cfg *config) filter(hosts sslparse.Hosts) sslparse.Hosts {
+	if cfg.onlyUp {
+		hosts = onlyUp(hosts)
+	}
+	filterPortStates(hosts, cfg.portStates)
+	stripRawOutput(hosts, cfg.includeRaw, cfg.verboseJSON)
+	if cfg.allowed != nil {
+		cfg.allowed.apply(hosts)
+	}
+	if cfg.minTLS != "" {
+		applyMinTLS(hosts, cfg.minTLS)
+	}
+	hosts.Summary = sslparse.Summarize(hosts.Hosts)
+	if cfg.findingsOnly {
+		hosts = findingsOnly(hosts)
+	}
+	if cfg.sort {
+		sortHosts(hosts)
+	}
+	return hosts
+}
+
+// onlyUp drops every host whose status is not "up".
+func onlyUp(hosts sslparse.Hosts) sslparse.Hosts {
+	filtered := hosts
+	filtered.Hosts = []sslparse.HostInfo{}
+	for _, host := range hosts.Hosts {
+		if host.Status == "up" {
+			filtered.Hosts = append(filtered.Hosts, host)
+		}
+	}
+	return filtered
+}
+
+// portStates are the port states nmap reports.
+var portStates = []string{"open", "closed", "filtered", "unfiltered", "open|filtered", "closed|filtered"}
+
+// validatePortStates checks that every -port-state entry is a state nmap
+// can report.
+func validatePortStates(states []string) error {
+	for _, state := range states {
+		known := false
+		for _, s := range portStates {
+			known = known || s == state
+		}
+		if !known {
+			return fmt.Errorf("invalid -port-state %q: want one of %s", state, strings.Join(portStates, ", "))
+		}
+	}
+	return nil
+}
+
+// filterPortStates keeps only the ports whose state is one of states.
+// Hosts without any matching port stay in the report with no ports.
+func filterPortStates(hosts sslparse.Hosts, states []string) {
+	keep := make(map[string]bool, len(states))
+	for _, state := range states {
+		keep[state] = true
+	}
+	for h := range hosts.Hosts {
+		ports := []sslparse.Port{}
+		for _, port := range hosts.Hosts[h].Ports {
+			if keep[port.State] {
+				ports = append(ports, port)
+			}
+		}
+		hosts.Hosts[h].Ports = ports
+	}
+}
+
+// findingsOnly keeps only the ports with at least one finding, and the
+// hosts that still have ports left.
+func findingsOnly(hosts sslparse.Hosts) sslparse.Hosts {
+	filtered := hosts
+	filtered.Hosts = []sslparse.HostInfo{}
+	for _, host := range hosts.Hosts {
+		var ports []sslparse.Port
+		for _, port := range host.Ports {
+			if len(portFindings(port)) > 0 {
+				ports = append(ports, port)
+			}
+		}
+		if len(ports) > 0 {
+			host.Ports = ports
+			filtered.Hosts = append(filtered.Hosts, host)
+		}
+	}
+	return filtered
+}
+
+// stripRawOutput clears the raw output the parser keeps on every port:
+// the output of all scripts unless keepScripts (-include-raw), and the
+// ssl-enum-ciphers output next to the parsed data unless keepTLS
+// (-verbose-json).
+func stripRawOutput(hosts sslparse.Hosts, keepScripts, keepTLS bool) {
+	for h := range hosts.Hosts {
+		for p := range hosts.Hosts[h].Ports {
+			port := &hosts.Hosts[h].Ports[p]
+			if !keepScripts {
+				port.RawScriptOutput = nil
+			}
+			if !keepTLS {
+				port.TLS.Raw = ""
+			}
+		}
+	}
+}
+
+// sortHosts puts the report in a stable order so that two scans of the
+// same network give identical reports: hosts and errors by IP, ports by
+// number and protocol, and hostnames and every cipher list alphabetically.
+// This drops the server preference order of the ciphers.
+func sortHosts(hosts sslparse.Hosts) {
+	sort.SliceStable(hosts.Hosts, func(i, j int) bool {
+		return lessIP(hosts.Hosts[i].IP, hosts.Hosts[j].IP)
+	})
+	sort.SliceStable(hosts.Errors, func(i, j int) bool {
+		return lessIP(hosts.Errors[i].IP, hosts.Errors[j].IP)
+	})
+	for h := range hosts.Hosts {
+		host := &hosts.Hosts[h]
+		sort.Strings(host.Hostnames)
+		sort.SliceStable(host.Ports, func(i, j int) bool {
+			a, b := host.Ports[i], host.Ports[j]
+			if a.ID != b.ID {
+				return a.ID < b.ID
+			}
+			return a.Protocol < b.Protocol
+		})
+		for p := range host.Ports {
+			tls := &host.Ports[p].TLS
+			for _, data := range []*sslparse.CipherData{&tls.SSL3, &tls.TLS10, &tls.TLS11, &tls.TLS12, &tls.TLS13} {
+				sortCiphers(data.Ciphers)
+				for _, names := range [][]string{data.WeakCiphers, data.NonFSCiphers, data.DisallowedCiphers} {
+					sort.Strings(names)
+				}
+			}
+		}
+	}
+}
+
+func sortCiphers(ciphers []sslparse.Cipher) {
+	sort.SliceStable(ciphers, func(i, j int) bool {
+		return ciphers[i].Name < ciphers[j].Name
+	})
+}
+
+// lessIP orders IP addresses numerically, IPv4 before IPv6, and anything
+// that does not parse as an IP after them, as a string.
+func lessIP(a, b string) bool {
+	ipA, errA := netip.ParseAddr(a)
+	ipB, errB := netip.ParseAddr(b)
+	switch {
+	case errA == nil && errB == nil:
+		return ipA.Less(ipB)
+	case errA == nil || errB == nil:
+		return errA == nil
+	default:
+		return a < b
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// hostIPs returns the IP of every host in hosts, in order.
+func hostIPs(hosts sslparse.Hosts) []string {
+	var ips []string
+	for _, host := range hosts.Hosts {
+		ips = append(ips, host.IP)
+	}
+	return ips
+}
+
+func TestOnlyUp(t *testing.T) {
+	hosts := sslparse.Hosts{Hosts: []sslparse.HostInfo{
+		{IP: "10.0.0.1", Status: "up"},
+		{IP: "10.0.0.2", Status: "down"},
+		{IP: "10.0.0.3", Status: "up"},
+	}}
+
+	cfg := &config{portStates: defaultPortStates}
+	if got, want := hostIPs(cfg.filter(hosts)), []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("without -only-up: hosts = %q, want %q", got, want)
+	}
+	cfg.onlyUp = true
+	if got, want := hostIPs(cfg.filter(hosts)), []string{"10.0.0.1", "10.0.0.3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("with -only-up: hosts = %q, want %q", got, want)
+	}
+}
+
+func TestFilterPortStates(t *testing.T) {
+	hosts := sslparse.Hosts{Hosts: []sslparse.HostInfo{
+		{IP: "10.0.0.1", Ports: []sslparse.Port{{ID: 443, State: "open"}, {ID: 8443, State: "filtered"}}},
+		{IP: "10.0.0.2", Ports: []sslparse.Port{{ID: 443, State: "closed"}}},
+	}}
+
+	filterPortStates(hosts, defaultPortStates)
+	if got := hosts.Hosts[0].Ports; len(got) != 1 || got[0].ID != 443 {
+		t.Errorf("10.0.0.1 ports = %+v, want only the open 443", got)
+	}
+	if got := hosts.Hosts[1].Ports; got == nil || len(got) != 0 {
+		t.Errorf("10.0.0.2 ports = %#v, want an empty list", got)
+	}
+}
+
+func TestFindingsOnly(t *testing.T) {
+	hosts := testHosts(t)
+	filtered := findingsOnly(hosts)
+	if got, want := hostIPs(filtered), []string{"93.184.216.34"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("hosts = %q, want %q", got, want)
+	}
+	// Port 80 has no TLS data and so no findings.
+	if ports := filtered.Hosts[0].Ports; len(ports) != 1 || ports[0].ID != 443 {
+		t.Errorf("ports = %+v, want only 443", ports)
+	}
+	if len(hosts.Hosts[0].Ports) != 2 {
+		t.Errorf("findingsOnly modified its input: %d ports left, want 2", len(hosts.Hosts[0].Ports))
+	}
+
+	clean := sslparse.Hosts{Hosts: []sslparse.HostInfo{
+		{IP: "10.0.0.1", Ports: []sslparse.Port{{ID: 443, State: "open"}}},
+	}}
+	if got := findingsOnly(clean).Hosts; got == nil || len(got) != 0 {
+		t.Errorf("clean scan: hosts = %#v, want an empty list", got)
+	}
+}
+
+func TestSortHosts(t *testing.T) {
+	tls12 := ciphers("TLS_RSA_WITH_AES_128_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256")
+	tls12.WeakCiphers = []string{"TLS_RSA_WITH_AES_128_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}
+	hosts := sslparse.Hosts{
+		Hosts: []sslparse.HostInfo{
+			{IP: "unresolved.example"},
+			{IP: "2001:db8::1"},
+			{IP: "10.0.0.10", Hostnames: []string{"b.example", "a.example"}, Ports: []sslparse.Port{
+				{ID: 443, Protocol: "udp"},
+				{ID: 8443, Protocol: "tcp"},
+				{ID: 443, Protocol: "tcp", TLS: sslparse.TLSVersions{TLS12: tls12}},
+			}},
+			{IP: "10.0.0.9"},
+		},
+		Errors: []sslparse.HostError{{IP: "10.0.0.2"}, {IP: "10.0.0.1"}},
+	}
+	sortHosts(hosts)
+
+	if got, want := hostIPs(hosts), []string{"10.0.0.9", "10.0.0.10", "2001:db8::1", "unresolved.example"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("hosts = %q, want %q", got, want)
+	}
+	if got := []string{hosts.Errors[0].IP, hosts.Errors[1].IP}; !reflect.DeepEqual(got, []string{"10.0.0.1", "10.0.0.2"}) {
+		t.Errorf("errors = %q, want them by IP", got)
+	}
+	host := hosts.Hosts[1]
+	if !reflect.DeepEqual(host.Hostnames, []string{"a.example", "b.example"}) {
+		t.Errorf("hostnames = %q, want them sorted", host.Hostnames)
+	}
+	var ports []string
+	for _, port := range host.Ports {
+		ports = append(ports, fmt.Sprintf("%d/%s", port.ID, port.Protocol))
+	}
+	if want := []string{"443/tcp", "443/udp", "8443/tcp"}; !reflect.DeepEqual(ports, want) {
+		t.Errorf("ports = %q, want %q", ports, want)
+	}
+	data := host.Ports[0].TLS.TLS12
+	want := []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_AES_128_CBC_SHA"}
+	if data.Ciphers[0].Name != want[0] || !reflect.DeepEqual(data.WeakCiphers, want) {
+		t.Errorf("ciphers = %v, weak ciphers = %q, want both sorted", data.Ciphers, data.WeakCiphers)
+	}
+}
+
+func TestFilteredPortStateReason(t *testing.T) {
+	stdout, _, err := runMain(t, "-xml", "testdata/scan.xml", "-port-state", "filtered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report sslparse.Hosts
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hostIPs(report), []string{"93.184.216.34", "10.0.0.2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("hosts = %q, want %q", got, want)
+	}
+	ports := report.Hosts[1].Ports
+	if len(ports) != 1 || ports[0].State != "filtered" || ports[0].StateReason != "no-response" {
+		t.Errorf("10.0.0.2 ports = %+v, want 443 filtered with reason no-response", ports)
+	}
+	if !strings.Contains(stdout, `"state_reason": "no-response"`) {
+		t.Errorf("report has no state_reason key:\n%s", stdout)
+	}
+}
+
+func TestVerboseJSONRaw(t *testing.T) {
+	for _, tt := range []struct {
+		args    []string
+		wantRaw bool
+	}{
+		{nil, false},
+		{[]string{"-include-raw"}, false},
+		{[]string{"-verbose-json"}, true},
+	} {
+		stdout, _, err := runMain(t, append(tt.args, "-xml", "testdata/scan.xml")...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var report sslparse.Hosts
+		if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+			t.Fatal(err)
+		}
+		raw := report.Hosts[0].Ports[1].TLS.Raw
+		if got := raw != ""; got != tt.wantRaw {
+			t.Errorf("%q: raw present = %v, want %v", tt.args, got, tt.wantRaw)
+		}
+		if tt.wantRaw && !strings.Contains(raw, "TLSv1.2:") {
+			t.Errorf("%q: raw = %q, want the ssl-enum-ciphers output", tt.args, raw)
+		}
+	}
+}
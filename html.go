@@ -0,0 +1,81 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"strings"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// htmlReport is a self-contained page: styles are inline and nothing is
+// loaded from elsewhere, so the file can be mailed or attached as is.
+// html/template escapes every value taken from the scan.
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"join":       strings.Join,
+	"gradeClass": gradeClass,
+	"issues":     portIssues,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>TLS scan report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1, h2 { font-weight: 600; }
+h2 .hostnames { font-weight: normal; color: #666; }
+table { border-collapse: collapse; margin: 0.5em 0 1.5em; }
+th, td { border: 1px solid #ddd; padding: 0.3em 0.7em; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+ul.issues { margin: 0; padding-left: 1.2em; color: #a00; }
+.grade { display: inline-block; min-width: 1.5em; text-align: center; font-weight: bold; border-radius: 3px; color: #fff; }
+.grade-good { background: #2a7d2a; }
+.grade-warn { background: #c98a00; }
+.grade-bad { background: #b22222; }
+.muted { color: #888; }
+</style>
+</head>
+<body>
+<h1>TLS scan report</h1>
+{{with .Meta}}<p class="muted">Scanned {{.StartedAt.Format "2006-01-02 15:04 MST"}} with nmap {{.NmapVersion}}</p>
+{{end}}{{with .Note}}<p class="muted">Note: {{.}}</p>
+{{end}}<h2>Summary</h2>
+<table>
+<tr><th>Hosts</th><td>{{.Summary.TotalHosts}} ({{.Summary.HostsUp}} up)</td></tr>
+<tr><th>Open ports</th><td>{{.Summary.TotalOpenPorts}}</td></tr>
+<tr><th>Ports with weak ciphers</th><td>{{.Summary.PortsWithWeakCiphers}}</td></tr>
+<tr><th>Ports with deprecated TLS</th><td>{{.Summary.PortsWithDeprecatedTLS}}</td></tr>
+</table>
+{{range .Hosts}}
+<h2>{{.IP}}{{with .Hostnames}} <span class="hostnames">({{join . ", "}})</span>{{end}}</h2>
+{{if not .Ports}}<p class="muted">No ports reported.</p>
+{{end}}{{range .Ports}}
+<h3>{{.ID}}/{{.Protocol}} {{.Service}} <span class="muted">{{.State}}</span>{{with .Grade}} <span class="grade {{gradeClass .}}">{{.}}</span>{{end}}</h3>
+{{with issues .}}<ul class="issues">{{range .}}<li>{{.}}</li>{{end}}</ul>
+{{end}}{{if .TLS.Offered}}<table>
+<tr><th>Version</th><th>Cipher</th><th>Key exchange</th><th>Strength</th></tr>
+{{range .TLS.Versions}}{{$version := .Name}}{{range .Data.Ciphers}}<tr><td>{{$version}}</td><td>{{.Name}}</td><td>{{.KeyInfo}}</td><td>{{.Strength}}</td></tr>
+{{end}}{{end}}</table>
+{{else}}<p class="muted">No TLS data.</p>
+{{end}}{{end}}{{end}}</body>
+</html>
+`))
+
+// writeHTML renders hosts as a standalone HTML page with a summary and a
+// cipher table for every port that has TLS data.
+func writeHTML(w io.Writer, hosts sslparse.Hosts) error {
+	return htmlReport.Execute(w, hosts)
+}
+
+// gradeClass is the CSS class for a grade, using the same bands as the
+// table format's colors.
+func gradeClass(grade string) string {
+	switch grade {
+	case "A", "B":
+		return "grade-good"
+	case "C":
+		return "grade-warn"
+	default:
+		return "grade-bad"
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"nmap-example/pkg/sslparse"
+)
+
+func TestWriteHTML(t *testing.T) {
+	var b bytes.Buffer
+	if err := writeHTML(&b, testHosts(t)); err != nil {
+		t.Fatal(err)
+	}
+	page := b.String()
+	for _, want := range []string{
+		`<h2>93.184.216.34 <span class="hostnames">(example.com)</span></h2>`,
+		`<h3>443/tcp https <span class="muted">open</span> <span class="grade grade-bad">F</span></h3>`,
+		`<li>offers deprecated TLSv1.0</li>`,
+		`<tr><td>TLSv1.0</td><td>TLS_RSA_WITH_RC4_128_SHA</td>`,
+		`<p class="muted">No TLS data.</p>`,
+		`<tr><th>Hosts</th><td>2 (2 up)</td></tr>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain %s", want)
+		}
+	}
+	if !strings.HasPrefix(page, "<!DOCTYPE html>") || !strings.HasSuffix(page, "</html>\n") {
+		t.Error("page is not a complete HTML document")
+	}
+}
+
+func TestWriteHTMLEscapesHostnames(t *testing.T) {
+	hosts := sslparse.Hosts{Hosts: []sslparse.HostInfo{{
+		IP:        "10.0.0.1",
+		Hostnames: []string{"a&b.example", `"quoted".example`},
+	}}}
+	var b bytes.Buffer
+	if err := writeHTML(&b, hosts); err != nil {
+		t.Fatal(err)
+	}
+	want := `<span class="hostnames">(a&amp;b.example, &#34;quoted&#34;.example)</span>`
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("page does not contain the escaped hostnames %s:\n%s", want, b.String())
+	}
+	if !strings.Contains(b.String(), "No ports reported.") {
+		t.Error("host without ports is not marked as such")
+	}
+}
+
+func TestGradeClass(t *testing.T) {
+	for grade, want := range map[string]string{
+		"A": "grade-good", "B": "grade-good", "C": "grade-warn", "D": "grade-bad", "F": "grade-bad",
+	} {
+		if got := gradeClass(grade); got != want {
+			t.Errorf("gradeClass(%q) = %q, want %q", grade, got, want)
+		}
+	}
+}
+
+func TestWriteHTMLEscapesScripts(t *testing.T) {
+	var b bytes.Buffer
+	if err := writeHTML(&b, hostileHosts()); err != nil {
+		t.Fatal(err)
+	}
+	page := b.String()
+	for _, bad := range []string{"<script>alert", "<img src"} {
+		if strings.Contains(page, bad) {
+			t.Errorf("page contains unescaped %q", bad)
+		}
+	}
+	for _, want := range []string{"&lt;script&gt;alert(1)&lt;/script&gt;", "&lt;img src=x onerror=alert(1)&gt;"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain the escaped %s", want)
+		}
+	}
+}
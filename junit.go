@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"nmap-example/pkg/sslparse"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit renders one test suite per host and one test case per port.
+// A port fails when it offers a deprecated TLS version or a weak cipher.
+func writeJUnit(w io.Writer, hosts sslparse.Hosts) error {
+	doc := junitTestSuites{Name: "nmap-tls"}
+	for _, host := range hosts.Hosts {
+		suite := junitTestSuite{Name: host.IP}
+		for _, port := range host.Ports {
+			tc := junitTestCase{
+				Name:      fmt.Sprintf("%d/%s %s", port.ID, port.Protocol, port.Service),
+				ClassName: host.IP,
+			}
+			if issues := portIssues(port); len(issues) > 0 {
+				tc.Failure = &junitFailure{
+					Message: fmt.Sprintf("%d TLS issue(s) found", len(issues)),
+					Type:    "tls",
+					Text:    strings.Join(issues, "\n"),
+				}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, tc)
+			suite.Tests++
+		}
+		doc.Suites = append(doc.Suites, suite)
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	var b bytes.Buffer
+	if err := writeJUnit(&b, testHosts(t)); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "report.junit.xml", b.Bytes())
+}
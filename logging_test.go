@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewLoggerLevel(t *testing.T) {
+	var b bytes.Buffer
+	logger, err := newLogger(&b, "warn", "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("debug message")
+	logger.Info("info message")
+	logger.Warn("warn message")
+	logger.Error("error message")
+
+	out := b.String()
+	for _, msg := range []string{"debug message", "info message"} {
+		if strings.Contains(out, msg) {
+			t.Errorf("-log-level warn logged %q:\n%s", msg, out)
+		}
+	}
+	for _, msg := range []string{"warn message", "error message"} {
+		if !strings.Contains(out, msg) {
+			t.Errorf("-log-level warn did not log %q:\n%s", msg, out)
+		}
+	}
+}
+
+func TestNewLoggerJSON(t *testing.T) {
+	var b bytes.Buffer
+	logger, err := newLogger(&b, "info", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("hello", "key", "value")
+	var entry map[string]any
+	if err := json.Unmarshal(b.Bytes(), &entry); err != nil {
+		t.Fatalf("log line is not JSON: %v\n%s", err, b.String())
+	}
+	if entry["msg"] != "hello" || entry["key"] != "value" {
+		t.Errorf("log entry = %v, want msg hello and key value", entry)
+	}
+}
+
+func TestNewLoggerInvalid(t *testing.T) {
+	if _, err := newLogger(&bytes.Buffer{}, "loud", "text"); err == nil {
+		t.Error("invalid level: got no error")
+	}
+	if _, err := newLogger(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("invalid format: got no error")
+	}
+}
@@ -2,169 +2,207 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	nmap "github.com/Ullaakut/nmap/v3"
+	"nmap-example/pkg/sslparse"
 )
 
-type CipherData struct {
-	Ciphers     []string `json:"ciphers"`
-	Compressors []string `json:"compressors"`
-	Preference  string   `json:"cipher_preference"`
-	Warnings    []string `json:"warnings"`
-}
+// deliveryTimeout bounds the uploads and notifications sent after the scan.
+const deliveryTimeout = time.Minute
 
-type TLSVersions struct {
-	TLS10    CipherData `json:"TLSv1.0"`
-	TLS11    CipherData `json:"TLSv1.1"`
-	TLS12    CipherData `json:"TLSv1.2"`
-	TLS13    CipherData `json:"TLSv1.3"`
-	Strength string     `json:"least_strength"`
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		slog.Error("run failed", "err", err)
+		if errors.Is(err, errFindings) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
 }
 
-type HostInfo struct {
-	IP        string   `json:"ip"`
-	Hostnames []string `json:"hostnames"`
-	Ports     []Port   `json:"ports"`
-}
+func run(args []string) error {
+	cfg, err := parseFlags(args)
+	if err != nil {
+		return err
+	}
 
-type Port struct {
-	ID       uint16      `json:"id"`
-	Protocol string      `json:"protocol"`
-	Service  string      `json:"service"`
-	State    string      `json:"state"`
-	TLS      TLSVersions `json:"ssl-enum-ciphers"`
-}
+	if cfg.showVersion {
+		return printVersion(os.Stdout)
+	}
+	if cfg.dryRun {
+		return cfg.printCommands(context.Background(), os.Stdout)
+	}
 
-type Hosts struct {
-	Hosts []HostInfo `json:"hosts"`
-}
+	// Cancel the scan on Ctrl+C or SIGTERM so the nmap child process is
+	// killed through the context instead of being left running.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-func main() {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	// A zero timeout means the scan may run for as long as it needs.
+	cancel := context.CancelFunc(func() {})
+	if cfg.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+	}
 	defer cancel()
 
-	hosts := []string{"google.com", "meta.com"}
-	ports := []string{"443", "80"}
-
-	// Run Nmap and get the output
-	scanner, err := nmap.NewScanner(
-		ctx,
-		nmap.WithTargets(hosts...),
-		nmap.WithPorts(ports...),
-		nmap.WithScripts("ssl-enum-ciphers"),
-	)
-	if err != nil {
-		fmt.Println("Error:", err)
-		return
+	if cfg.resumeFile != "" {
+		return cfg.resumeScan(ctx)
 	}
 
-	result, warnings, err := scanner.Run()
-	if err != nil {
-		fmt.Println("Error:", err)
-		return
+	// Load the input files up front so a bad file fails before a long scan.
+	var baseline sslparse.Hosts
+	if cfg.baselinePath != "" {
+		if baseline, err = readBaseline(cfg.baselinePath); err != nil {
+			return err
+		}
+	}
+	if cfg.allowedCiphersFile != "" {
+		if cfg.allowed, err = readAllowedCiphers(cfg.allowedCiphersFile); err != nil {
+			return err
+		}
 	}
 
-	if warnings != nil && len(*warnings) > 0 {
-		fmt.Println("Warnings:", warnings)
+	if cfg.streamsLive() {
+		if cfg.live, err = cfg.openHostStream(cfg.reports[0].path); err != nil {
+			return fmt.Errorf("writing report: %w", err)
+		}
+		defer cfg.live.close()
 	}
 
-	parsedHosts := parseNmapOutput(result)
-	jsonData, err := json.MarshalIndent(parsedHosts, "", "  ")
+	var scanErr error
+	result, warnings, err := cfg.scan(ctx)
 	if err != nil {
-		fmt.Println("Error:", err)
-		return
+		// When the scan is interrupted or times out, nmap is killed and
+		// scanBatch recovers the hosts it had already finished from the
+		// XML written so far, see sslscan.Run. Report those rather than
+		// nothing, and fail once the report has been written.
+		if ctx.Err() == nil || result == nil || len(result.Hosts) == 0 {
+			return err
+		}
+		slog.Warn("scan interrupted, reporting partial results", "err", err)
+		scanErr = fmt.Errorf("scan interrupted: %w", ctx.Err())
+	}
+
+	if warnings != nil {
+		for _, warning := range *warnings {
+			slog.Warn("nmap warning", "message", warning)
+		}
 	}
-	fmt.Println(string(jsonData))
-}
 
-func parseNmapOutput(result *nmap.Run) Hosts {
-	hosts := Hosts{}
 	if len(result.Hosts) == 0 {
-		fmt.Println("No hosts found.")
-		return hosts
-	}
-	for _, host := range result.Hosts {
-		hostInfo := HostInfo{}
-		hostInfo.IP = host.Addresses[0].String()
-		for _, hostname := range host.Hostnames {
-			hostInfo.Hostnames = append(hostInfo.Hostnames, hostname.Name)
-		}
-
-		for _, port := range host.Ports {
-			p := Port{
-				ID:       port.ID,
-				Protocol: port.Protocol,
-				Service:  port.Service.Name,
-				State:    port.State.State,
-			}
-			for _, script := range port.Scripts {
-				tlsVersions, strength := parseOutput(script.Output)
-				p.TLS.TLS10 = tlsVersions["TLSv1.0"]
-				p.TLS.TLS11 = tlsVersions["TLSv1.1"]
-				p.TLS.TLS12 = tlsVersions["TLSv1.2"]
-				p.TLS.TLS13 = tlsVersions["TLSv1.3"]
-				p.TLS.Strength = strength
+		slog.Warn("no hosts found")
+	}
+
+	if cfg.live != nil {
+		// -xml does not run nmap, so nothing was written while it ran.
+		if cfg.xmlFile != "" {
+			write := cfg.live.batch()
+			for _, host := range result.Hosts {
+				write(host)
 			}
-			hostInfo.Ports = append(hostInfo.Ports, p)
 		}
-		hosts.Hosts = append(hosts.Hosts, hostInfo)
+		if err := cfg.live.close(); err != nil {
+			return fmt.Errorf("writing report: %w", err)
+		}
+		if cfg.output != "" {
+			slog.Info("report written", "path", cfg.output)
+		}
+		return scanErr
 	}
 
-	return hosts
-}
+	if cfg.stream {
+		if err := cfg.writeStream(cfg.output, cfg.format, result); err != nil {
+			return fmt.Errorf("writing report: %w", err)
+		}
+		if cfg.output != "" {
+			slog.Info("report written", "path", cfg.output)
+		}
+		return scanErr
+	}
 
-func parseOutput(output string) (map[string]CipherData, string) {
-	tlsVersions := make(map[string]CipherData)
-	var strength string
-	lines := strings.Split(output, "\n")
-	var key string
-	var currentTLSVersion string
-
-	for _, line := range lines {
-		if strings.Contains(line, "TLSv") {
-			// Start of a new TLS version section
-			currentTLSVersion = strings.Replace(strings.TrimSpace(line), ":", "", -1)
-			tlsVersions[currentTLSVersion] = CipherData{}
-			key = "" // Reset key when starting a new section
-		} else if strings.Contains(line, "ciphers") ||
-			strings.Contains(line, "compressors") ||
-			strings.Contains(line, "cipher preference") ||
-			strings.Contains(line, "warnings") {
-			// Detect the key for the current section
-			key = strings.Replace(strings.TrimSpace(line), ":", "", -1)
-		}
-
-		if key != "" && currentTLSVersion != "" && !strings.Contains(line, "least strength") {
-			// Append line to the corresponding field in CipherData
-			data := tlsVersions[currentTLSVersion]
-			if key == "ciphers" {
-				c := strings.TrimSpace(line)
-				if !strings.Contains(c, "ciphers") {
-					data.Ciphers = append(data.Ciphers, c)
-				}
-			} else if key == "compressors" {
-				c := strings.TrimSpace(line)
-				if c != "NULL" && !strings.Contains(c, "compressors") {
-					data.Compressors = append(data.Compressors, c)
-				}
-			} else if key == "warnings" {
-				c := strings.TrimSpace(line)
-				if !strings.Contains(c, "warnings") {
-					data.Warnings = append(data.Warnings, c)
-				}
-			} else if strings.Contains(key, "cipher preference") {
-				data.Preference = strings.TrimSpace(strings.Split(key, " ")[2])
-			}
-			tlsVersions[currentTLSVersion] = data
-		} else if strings.Contains(line, "least strength") {
-			l := strings.Split(line, " ")
-			strength = strings.TrimSpace(l[len(l)-1])
+	parsedHosts := sslparse.ParseRun(result)
+	for _, host := range parsedHosts.Hosts {
+		slog.Debug("parsed host", "ip", host.IP, "status", host.Status, "ports", len(host.Ports))
+	}
+	for _, hostErr := range parsedHosts.Errors {
+		slog.Warn("parse error", "ip", hostErr.IP, "err", hostErr.Message)
+	}
+	parsedHosts = cfg.filter(parsedHosts)
+	if cfg.findingsOnly && len(parsedHosts.Hosts) == 0 {
+		slog.Info("no findings, the report lists no hosts")
+	}
+	for _, r := range cfg.reports {
+		if err := writeOutput(r.path, r.format, cfg.reportOptions(), parsedHosts); err != nil {
+			return fmt.Errorf("writing %s report: %w", r.format, err)
+		}
+		if r.path != "" {
+			slog.Info("report written", "path", r.path)
+		}
+	}
+	if n := len(parsedHosts.Errors); n > 0 {
+		slog.Warn("some hosts could not be fully parsed", "errors", n)
+	}
+
+	if cfg.baselinePath != "" {
+		diff := diffHosts(baseline, parsedHosts)
+		for _, change := range diff.Changes() {
+			slog.Info("change since baseline", "change", change)
+		}
+		if diff.Empty() {
+			slog.Info("no changes since baseline", "path", cfg.baselinePath)
+		}
+	}
+
+	if cfg.sqlitePath != "" {
+		if err := storeSQLite(cfg.sqlitePath, parsedHosts); err != nil {
+			return fmt.Errorf("storing results: %w", err)
 		}
+		slog.Info("results stored", "path", cfg.sqlitePath)
 	}
 
-	return tlsVersions, strength
+	if cfg.promFile != "" {
+		if err := writePromFile(cfg.promFile, parsedHosts); err != nil {
+			return fmt.Errorf("writing metrics: %w", err)
+		}
+		slog.Info("metrics written", "path", cfg.promFile)
+	}
+
+	// Deliver the report with a context of its own: when the scan was
+	// interrupted or timed out, ctx is already done and every delivery
+	// would fail at once, although there are partial results to send.
+	deliverCtx, cancelDelivery := context.WithTimeout(context.WithoutCancel(ctx), deliveryTimeout)
+	defer cancelDelivery()
+
+	if cfg.s3URI != "" {
+		if err := uploadS3(deliverCtx, cfg.s3URI, cfg.compact, parsedHosts); err != nil {
+			return fmt.Errorf("uploading to S3: %w", err)
+		}
+		slog.Info("report uploaded", "uri", cfg.s3URI)
+	}
+
+	if cfg.webhook != "" {
+		if err := postWebhook(deliverCtx, cfg.webhook, cfg.webhookHeaders, parsedHosts); err != nil {
+			return fmt.Errorf("posting to webhook: %w", err)
+		}
+	}
+
+	if cfg.slackWebhook != "" {
+		if err := notifySlack(deliverCtx, cfg.slackWebhook, cfg.slackAlways, parsedHosts); err != nil {
+			return fmt.Errorf("notifying Slack: %w", err)
+		}
+	}
+
+	if violations := cfg.violations(parsedHosts); len(violations) > 0 {
+		for _, v := range violations {
+			slog.Error("policy violation", "finding", v)
+		}
+		return errFindings
+	}
+	return scanErr
 }
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	nmap "github.com/Ullaakut/nmap/v3"
+
+	"nmap-example/pkg/sslparse"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// testHosts returns the report for testdata/scan.xml, filtered the way
+// run does by default.
+func testHosts(t *testing.T) sslparse.Hosts {
+	t.Helper()
+	result := &nmap.Run{}
+	if err := result.FromFile("testdata/scan.xml"); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config{portStates: defaultPortStates}
+	return cfg.filter(sslparse.ParseRun(result))
+}
+
+// checkGolden compares got with testdata/name, or rewrites the file when
+// the tests are run with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\n%s", path, got)
+	}
+}
+
+// resetFlags replaces the command line flag set with an empty one that
+// returns errors instead of exiting, and restores the test binary's own
+// flag set, with -update, when the test ends.
+func resetFlags(t *testing.T) {
+	t.Helper()
+	saved := flag.CommandLine
+	t.Cleanup(func() { flag.CommandLine = saved })
+	flag.CommandLine = flag.NewFlagSet("nmap-example", flag.ContinueOnError)
+	flag.CommandLine.SetOutput(io.Discard)
+}
+
+// runMain calls run with args and a fresh flag set, and returns what it
+// wrote to stdout and stderr.
+func runMain(t *testing.T, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+	resetFlags(t)
+	logger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(logger) })
+
+	files := make([]*os.File, 2)
+	for i := range files {
+		f, ferr := os.CreateTemp(t.TempDir(), "out")
+		if ferr != nil {
+			t.Fatal(ferr)
+		}
+		defer f.Close()
+		files[i] = f
+	}
+	oldStdout, oldStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = files[0], files[1]
+	err = run(args)
+	os.Stdout, os.Stderr = oldStdout, oldStderr
+
+	var out [2]string
+	for i, f := range files {
+		data, rerr := os.ReadFile(f.Name())
+		if rerr != nil {
+			t.Fatal(rerr)
+		}
+		out[i] = string(data)
+	}
+	return out[0], out[1], err
+}
+
+func TestWarningsGoToStderr(t *testing.T) {
+	stdout, stderr, err := runMain(t, "-nmap-path", "testdata/fake-nmap", "-targets", "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stderr, "fake-nmap does not scan anything") {
+		t.Errorf("stderr does not contain the nmap warning:\n%s", stderr)
+	}
+	if !json.Valid([]byte(stdout)) {
+		t.Errorf("stdout is not valid JSON:\n%s", stdout)
+	}
+}
+
+func TestXMLFile(t *testing.T) {
+	stdout, _, err := runMain(t, "-xml", "testdata/scan.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hosts sslparse.Hosts
+	if err := json.Unmarshal([]byte(stdout), &hosts); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only open ports are reported by default, so the filtered port of
+	// 10.0.0.2 is left out.
+	type port struct {
+		ID    uint16
+		State string
+		Grade string
+		TLS   []string
+	}
+	type host struct {
+		IP        string
+		Hostnames []string
+		Ports     []port
+	}
+	want := []host{
+		{IP: "93.184.216.34", Hostnames: []string{"example.com"}, Ports: []port{
+			{ID: 80, State: "open"},
+			{ID: 443, State: "open", Grade: "F", TLS: []string{"TLSv1.0", "TLSv1.2", "TLSv1.3"}},
+		}},
+		{IP: "10.0.0.2", Ports: []port{}},
+	}
+	var got []host
+	for _, h := range hosts.Hosts {
+		gh := host{IP: h.IP, Hostnames: h.Hostnames, Ports: []port{}}
+		for _, p := range h.Ports {
+			gh.Ports = append(gh.Ports, port{p.ID, p.State, p.Grade, p.TLS.Offered()})
+		}
+		got = append(got, gh)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hosts =\n%+v\nwant\n%+v", got, want)
+	}
+	if hosts.Meta == nil || hosts.Meta.NmapVersion != "7.94" {
+		t.Errorf("scan metadata = %+v, want nmap version 7.94", hosts.Meta)
+	}
+}
+
+func TestRetriesRange(t *testing.T) {
+	for _, retries := range []string{"-1", "11"} {
+		_, _, err := runMain(t, "-retries", retries, "-targets", "example.com", "-dry-run")
+		if err == nil || !strings.Contains(err.Error(), "invalid -retries") {
+			t.Errorf("-retries %s: err = %v, want an invalid -retries error", retries, err)
+		}
+	}
+}
+
+func TestTopPortsExcludesPorts(t *testing.T) {
+	_, _, err := runMain(t, "-targets", "example.com", "-top-ports", "100", "-ports", "443", "-dry-run")
+	if err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Errorf("err = %v, want -top-ports and -ports to be mutually exclusive", err)
+	}
+
+	stdout, _, err := runMain(t, "-targets", "example.com", "-top-ports", "100", "-dry-run", "-nmap-path", "testdata/fake-nmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "--top-ports 100") || strings.Contains(stdout, " -p ") {
+		t.Errorf("command = %q, want --top-ports 100 and no -p", stdout)
+	}
+}
+
+func TestVersionShortCircuits(t *testing.T) {
+	// No targets and an invalid -timing: -version is handled before
+	// anything else is checked, and nmap is never run.
+	stdout, _, err := runMain(t, "-version", "-timing", "9", "-nmap-path", "/nonexistent/nmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "nmap-example dev (commit none, built unknown)\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+}
+
+func TestEmptyHostsList(t *testing.T) {
+	dir := t.TempDir()
+	const head = `<?xml version="1.0"?>
+<nmaprun scanner="nmap" args="nmap 10.0.0.9" start="1700000000" version="7.94">
+`
+	const tail = `<runstats><finished time="1700000001" elapsed="1.00"/></runstats>
+</nmaprun>
+`
+	empty := filepath.Join(dir, "empty.xml")
+	down := filepath.Join(dir, "down.xml")
+	for path, hosts := range map[string]string{
+		empty: "",
+		down:  `<host><status state="down" reason="no-response"/><address addr="10.0.0.9" addrtype="ipv4"/></host>` + "\n",
+	} {
+		if err := os.WriteFile(path, []byte(head+hosts+tail), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"-xml", empty},
+		{"-xml", down, "-only-up"},
+	} {
+		stdout, stderr, err := runMain(t, append(args, "-compact", "-format", "json")...)
+		if err != nil {
+			t.Fatalf("%q: %v", args, err)
+		}
+		if !strings.Contains(stdout, `"hosts":[]`) {
+			t.Errorf("%q: stdout = %s, want an empty hosts list", args, stdout)
+		}
+		if args[1] == empty && !strings.Contains(stderr, "no hosts found") {
+			t.Errorf("%q: stderr = %q, want the no hosts message", args, stderr)
+		}
+	}
+}
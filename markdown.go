@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// writeMarkdown renders one section per host, each with a table of its
+// ports. Ports without TLS data still list their service and state.
+// Values reported by the scanned hosts, such as hostnames and service
+// names, go through mdEscape.
+func writeMarkdown(w io.Writer, hosts sslparse.Hosts) error {
+	var b strings.Builder
+	b.WriteString("# TLS scan report\n")
+	sum := hosts.Summary
+	b.WriteString("\n## Summary\n\n")
+	fmt.Fprintf(&b, "- Hosts: %d (%d up)\n", sum.TotalHosts, sum.HostsUp)
+	fmt.Fprintf(&b, "- Open ports: %d\n", sum.TotalOpenPorts)
+	fmt.Fprintf(&b, "- Ports with weak ciphers: %d\n", sum.PortsWithWeakCiphers)
+	fmt.Fprintf(&b, "- Ports with deprecated TLS: %d\n", sum.PortsWithDeprecatedTLS)
+	for _, host := range hosts.Hosts {
+		b.WriteString("\n## " + mdEscape(host.IP))
+		if len(host.Hostnames) > 0 {
+			b.WriteString(" (" + mdEscape(strings.Join(host.Hostnames, ", ")) + ")")
+		}
+		b.WriteString("\n\n")
+
+		if len(host.Ports) == 0 {
+			b.WriteString("No ports reported.\n")
+			continue
+		}
+		b.WriteString("| Host | Port | Service | State | TLS versions | Grade |\n")
+		b.WriteString("|------|------|---------|-------|--------------|-------|\n")
+		for _, port := range host.Ports {
+			fmt.Fprintf(&b, "| %s | %d/%s | %s | %s | %s | %s |\n",
+				mdEscape(host.IP), port.ID, mdEscape(port.Protocol), mdEscape(port.Service), mdEscape(port.State),
+				strings.Join(port.TLS.Offered(), ", "), port.Grade)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mdEscaper backslash-escapes the characters with a meaning in Markdown,
+// including the table separator, and turns HTML into entities, since most
+// Markdown renderers pass raw HTML through.
+var mdEscaper = strings.NewReplacer(
+	"\\", "\\\\", "`", "\\`", "*", "\\*", "_", "\\_", "[", "\\[", "]", "\\]",
+	"#", "\\#", "|", "\\|", "!", "\\!", "~", "\\~",
+	"&", "&amp;", "<", "&lt;", ">", "&gt;",
+	"\r", " ", "\n", " ",
+)
+
+// mdEscape makes s safe to embed in a Markdown heading or table cell.
+func mdEscape(s string) string {
+	return mdEscaper.Replace(s)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"nmap-example/pkg/sslparse"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	var b bytes.Buffer
+	if err := writeMarkdown(&b, testHosts(t)); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "report.md", b.Bytes())
+}
+
+// hostileHosts is a report whose scanned values try to inject markup.
+func hostileHosts() sslparse.Hosts {
+	return sslparse.Hosts{Hosts: []sslparse.HostInfo{{
+		IP:        "10.0.0.1",
+		Hostnames: []string{"<script>alert(1)</script>", "*bold* | [x](y) `code`\nnext"},
+		Ports:     []sslparse.Port{{ID: 443, Protocol: "tcp", Service: "<img src=x onerror=alert(1)>", State: "open|filtered"}},
+	}}}
+}
+
+func TestWriteMarkdownEscapes(t *testing.T) {
+	var b bytes.Buffer
+	if err := writeMarkdown(&b, hostileHosts()); err != nil {
+		t.Fatal(err)
+	}
+	report := b.String()
+	for _, bad := range []string{"<script>", "<img", "*bold*", "[x](y)", "`code`", " | [", "open|filtered"} {
+		if strings.Contains(report, bad) {
+			t.Errorf("report contains unescaped %q:\n%s", bad, report)
+		}
+	}
+	wantHeading := "## 10.0.0.1 (&lt;script&gt;alert(1)&lt;/script&gt;, \\*bold\\* \\| \\[x\\](y) \\`code\\` next)\n"
+	if !strings.Contains(report, wantHeading) {
+		t.Errorf("report does not contain the heading %q:\n%s", wantHeading, report)
+	}
+	wantRow := "| 10.0.0.1 | 443/tcp | &lt;img src=x onerror=alert(1)&gt; | open\\|filtered |  |  |\n"
+	if !strings.Contains(report, wantRow) {
+		t.Errorf("report does not contain the row %q:\n%s", wantRow, report)
+	}
+}
+
+func TestMdEscape(t *testing.T) {
+	for s, want := range map[string]string{
+		"example.com":   "example.com",
+		"a_b#c!d~e":     "a\\_b\\#c\\!d\\~e",
+		"\\":            "\\\\",
+		"a&b":           "a&amp;b",
+		"line\r\nbreak": "line  break",
+	} {
+		if got := mdEscape(s); got != want {
+			t.Errorf("mdEscape(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
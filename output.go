@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"nmap-example/pkg/sslparse"
+)
+
+var formats = []string{"json", "ndjson", "csv", "md", "table", "junit", "sarif", "yaml", "html"}
+
+func validFormat(format string) bool {
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// report is one rendering of the scan results: a format and the file it
+// is written to, or stdout for an empty path.
+type report struct {
+	format string
+	path   string
+}
+
+// parseReports pairs the comma-separated -format and -output values. A
+// single format keeps -output as one path, so existing invocations are
+// unaffected; several formats need exactly one output file each.
+func parseReports(format, output string) ([]report, error) {
+	formats := strings.Split(format, ",")
+	for _, f := range formats {
+		if !validFormat(f) {
+			return nil, fmt.Errorf("unknown format %q", f)
+		}
+	}
+	if len(formats) == 1 {
+		return []report{{format: format, path: output}}, nil
+	}
+
+	var paths []string
+	if output != "" {
+		paths = strings.Split(output, ",")
+	}
+	if len(paths) != len(formats) {
+		return nil, fmt.Errorf("%d -format values need %d comma-separated -output files, got %d", len(formats), len(formats), len(paths))
+	}
+	reports := make([]report, len(formats))
+	for i := range formats {
+		if paths[i] == "" {
+			return nil, fmt.Errorf("-output file %d is empty", i+1)
+		}
+		reports[i] = report{format: formats[i], path: paths[i]}
+	}
+	return reports, nil
+}
+
+// reportOptions are the command line settings that affect how a report is
+// rendered, as opposed to what it contains.
+type reportOptions struct {
+	// compact disables indentation in the json format.
+	compact bool
+	// color enables ANSI colors in the table format.
+	color bool
+}
+
+// writeOutput renders hosts in the given format to path, or to stdout when
+// path is empty.
+func writeOutput(path, format string, opts reportOptions, hosts sslparse.Hosts) error {
+	if path == "" {
+		return writeReport(os.Stdout, format, opts, hosts)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := writeReport(f, format, opts, hosts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeReport renders hosts in format to w.
+func writeReport(w io.Writer, format string, opts reportOptions, hosts sslparse.Hosts) error {
+	switch format {
+	case "json":
+		return writeJSON(w, hosts, opts.compact)
+	case "ndjson":
+		return writeNDJSON(w, hosts)
+	case "csv":
+		return writeCSV(w, hosts)
+	case "md":
+		return writeMarkdown(w, hosts)
+	case "table":
+		return writeTable(w, hosts, opts.color)
+	case "junit":
+		return writeJUnit(w, hosts)
+	case "sarif":
+		return writeSARIF(w, hosts)
+	case "yaml":
+		return writeYAML(w, hosts)
+	case "html":
+		return writeHTML(w, hosts)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// writeJSON writes hosts as a single JSON document, indented for reading
+// unless compact is set.
+func writeJSON(w io.Writer, hosts sslparse.Hosts, compact bool) error {
+	var jsonData []byte
+	var err error
+	if compact {
+		jsonData, err = json.Marshal(hosts)
+	} else {
+		jsonData, err = json.MarshalIndent(hosts, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(jsonData))
+	return err
+}
+
+// writeNDJSON writes each host as a compact JSON object on its own line, so
+// every line can be ingested independently.
+func writeNDJSON(w io.Writer, hosts sslparse.Hosts) error {
+	enc := json.NewEncoder(w)
+	for _, host := range hosts.Hosts {
+		if err := enc.Encode(host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCSV writes one row per (host, port, TLS version, cipher). Ports
+// without any TLS data still get a single row with empty TLS columns.
+func writeCSV(w io.Writer, hosts sslparse.Hosts) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"ip", "hostname", "port", "protocol", "service", "state", "tls_version", "cipher", "least_strength"})
+	if err != nil {
+		return err
+	}
+
+	for _, host := range hosts.Hosts {
+		hostname := strings.Join(host.Hostnames, ";")
+		for _, port := range host.Ports {
+			row := []string{host.IP, hostname, strconv.Itoa(int(port.ID)), port.Protocol, port.Service, port.State}
+
+			wrote := false
+			for _, v := range port.TLS.Versions() {
+				for _, cipher := range v.Data.Ciphers {
+					if err := cw.Write(append(row, v.Name, cipher.Name, port.TLS.Strength)); err != nil {
+						return err
+					}
+					wrote = true
+				}
+			}
+			if !wrote {
+				if err := cw.Write(append(row, "", "", "")); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
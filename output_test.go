@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nmap-example/pkg/sslparse"
+)
+
+func TestWriteJSONCompact(t *testing.T) {
+	hosts := testHosts(t)
+	var compact, indented bytes.Buffer
+	if err := writeJSON(&compact, hosts, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeJSON(&indented, hosts, false); err != nil {
+		t.Fatal(err)
+	}
+
+	out := compact.String()
+	if !strings.HasSuffix(out, "\n") || strings.Contains(strings.TrimSuffix(out, "\n"), "\n") {
+		t.Errorf("compact output is not a single line:\n%s", out)
+	}
+	if !strings.Contains(indented.String(), "\n  ") {
+		t.Error("default output is not indented")
+	}
+	var a, b any
+	if err := json.Unmarshal(compact.Bytes(), &a); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(indented.Bytes(), &b); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Error("compact and indented output hold different data")
+	}
+}
+
+func TestJSONOfferedCiphersAndNote(t *testing.T) {
+	for _, args := range [][]string{{}, {"-stream"}} {
+		stdout, _, err := runMain(t, append(args, "-xml", "testdata/scan.xml", "-format", "json")...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var report struct {
+			Note  string `json:"note"`
+			Hosts []struct {
+				Ports []struct {
+					TLS struct {
+						TLS12 map[string]json.RawMessage `json:"TLSv1.2"`
+					} `json:"ssl-enum-ciphers"`
+				} `json:"ports"`
+			} `json:"hosts"`
+		}
+		if err := json.Unmarshal([]byte(stdout), &report); err != nil {
+			t.Fatalf("%q: %v", args, err)
+		}
+		if report.Note != sslparse.OfferedNote {
+			t.Errorf("%q: note = %q, want %q", args, report.Note, sslparse.OfferedNote)
+		}
+		tls12 := report.Hosts[0].Ports[1].TLS.TLS12
+		offered, ok := tls12["offered_ciphers"]
+		if !ok {
+			t.Fatalf("%q: TLSv1.2 has no offered_ciphers key", args)
+		}
+		if string(offered) != string(tls12["ciphers"]) || string(offered) == "null" {
+			t.Errorf("%q: offered_ciphers = %s, want the ciphers list %s", args, offered, tls12["ciphers"])
+		}
+	}
+}
+
+func TestParseReports(t *testing.T) {
+	got, err := parseReports("json", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []report{{format: "json"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("single format = %+v, want %+v", got, want)
+	}
+
+	got, err = parseReports("json,md,sarif", "scan.json,scan.md,scan.sarif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []report{{"json", "scan.json"}, {"md", "scan.md"}, {"sarif", "scan.sarif"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("several formats = %+v, want %+v", got, want)
+	}
+
+	for _, args := range [][2]string{
+		{"json,xlsx", "a,b"},
+		{"json,md", ""},
+		{"json,md", "scan.json"},
+		{"json,md", "scan.json,"},
+		{"pdf", ""},
+	} {
+		if _, err := parseReports(args[0], args[1]); err == nil {
+			t.Errorf("parseReports(%q, %q): want an error", args[0], args[1])
+		}
+	}
+}
+
+func TestMultipleReports(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath, mdPath := filepath.Join(dir, "scan.json"), filepath.Join(dir, "scan.md")
+	stdout, _, err := runMain(t, "-xml", "testdata/scan.xml", "-format", "json,md", "-output", jsonPath+","+mdPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout != "" {
+		t.Errorf("stdout = %q, want every report in its file", stdout)
+	}
+
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hosts sslparse.Hosts
+	if err := json.Unmarshal(data, &hosts); err != nil || len(hosts.Hosts) != 2 {
+		t.Errorf("json report: %d hosts, err %v", len(hosts.Hosts), err)
+	}
+	data, err = os.ReadFile(mdPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "# TLS scan report\n") {
+		t.Errorf("md report does not start with its title:\n%s", data)
+	}
+}
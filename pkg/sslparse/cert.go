@@ -0,0 +1,67 @@
+package sslparse
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const sslCert = "ssl-cert"
+
+// certTimeLayout is the timestamp format used by the ssl-cert script.
+const certTimeLayout = "2006-01-02T15:04:05"
+
+// Certificate is the parsed output of the ssl-cert script.
+type Certificate struct {
+	Subject         string    `json:"subject" yaml:"subject"`
+	Issuer          string    `json:"issuer" yaml:"issuer"`
+	NotBefore       time.Time `json:"not_before" yaml:"not_before"`
+	NotAfter        time.Time `json:"not_after" yaml:"not_after"`
+	DaysUntilExpiry int       `json:"days_until_expiry" yaml:"days_until_expiry"`
+	// Error is set when a validity date could not be parsed, in which
+	// case that date is left zero.
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// parseCertOutput parses the output of the ssl-cert script, computing the
+// days until expiry relative to the current time.
+func parseCertOutput(output string) Certificate {
+	return parseCert(output, time.Now())
+}
+
+// parseCert is parseCertOutput with the current time passed in.
+func parseCert(output string, now time.Time) Certificate {
+	var cert Certificate
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Subject":
+			cert.Subject = value
+		case "Issuer":
+			cert.Issuer = value
+		case "Not valid before":
+			cert.NotBefore = cert.parseTime(key, value)
+		case "Not valid after":
+			cert.NotAfter = cert.parseTime(key, value)
+		}
+	}
+	if !cert.NotAfter.IsZero() {
+		cert.DaysUntilExpiry = int(cert.NotAfter.Sub(now).Hours() / 24)
+	}
+	return cert
+}
+
+// parseTime parses the value of the named ssl-cert date field, recording
+// the first failure in cert.Error.
+func (cert *Certificate) parseTime(field, value string) time.Time {
+	t, err := time.Parse(certTimeLayout, value)
+	if err != nil && cert.Error == "" {
+		cert.Error = fmt.Sprintf("invalid %q date %q", field, value)
+	}
+	return t
+}
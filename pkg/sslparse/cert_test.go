@@ -0,0 +1,43 @@
+package sslparse
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCert(t *testing.T) {
+	output := readFixture(t, "ssl_cert.txt")
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	cert := parseCert(output, now)
+	want := Certificate{
+		Subject:         "commonName=example.com/organizationName=Example Inc/countryName=US",
+		Issuer:          "commonName=DigiCert TLS RSA SHA256 2020 CA1/organizationName=DigiCert Inc/countryName=US",
+		NotBefore:       time.Date(2026, 1, 13, 0, 0, 0, 0, time.UTC),
+		NotAfter:        time.Date(2026, 10, 19, 23, 59, 59, 0, time.UTC),
+		DaysUntilExpiry: 5,
+	}
+	if cert != want {
+		t.Errorf("parseCert =\n%+v\nwant\n%+v", cert, want)
+	}
+
+	if days := parseCert(output, want.NotAfter.Add(48*time.Hour)).DaysUntilExpiry; days != -2 {
+		t.Errorf("expired 2 days ago: DaysUntilExpiry = %d, want -2", days)
+	}
+}
+
+func TestParseCertInvalidDate(t *testing.T) {
+	output := strings.Replace(readFixture(t, "ssl_cert.txt"), "2026-10-19T23:59:59", "Oct 19 23:59:59 2026 GMT", 1)
+
+	cert := parseCert(output, time.Now())
+	if cert.Error == "" {
+		t.Error("unparseable expiry date: Error is empty")
+	}
+	if !cert.NotAfter.IsZero() || cert.DaysUntilExpiry != 0 {
+		t.Errorf("NotAfter, DaysUntilExpiry = %s, %d, want zero values", cert.NotAfter, cert.DaysUntilExpiry)
+	}
+	if cert.NotBefore.IsZero() {
+		t.Error("the valid start date was not parsed")
+	}
+}
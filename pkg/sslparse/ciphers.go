@@ -0,0 +1,66 @@
+package sslparse
+
+import "strings"
+
+// weakPatterns are substrings of cipher suite names that indicate a broken
+// or export-grade algorithm.
+var weakPatterns = []string{"RC4", "3DES", "DES", "NULL", "EXPORT", "MD5"}
+
+// weakCiphers returns the ciphers in data whose name matches one of the
+// weak patterns, compared case-insensitively.
+func weakCiphers(data CipherData) []string {
+	var weak []string
+	for _, cipher := range data.Ciphers {
+		upper := strings.ToUpper(cipher.Name)
+		for _, pattern := range weakPatterns {
+			if strings.Contains(upper, pattern) {
+				weak = append(weak, cipher.Name)
+				break
+			}
+		}
+	}
+	return weak
+}
+
+// classifyKeyExchange returns the key exchange of a cipher suite name such
+// as "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256": "ECDHE", "DHE", "ECDH",
+// "DH", "RSA", "PSK" or "SRP". TLS 1.3 suites, which do not name a key
+// exchange because it is always ephemeral, are reported as "TLSv1.3".
+// Anything else is "unknown".
+func classifyKeyExchange(cipher string) string {
+	name := strings.ToUpper(cipher)
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "TLS_"), "SSL_")
+	kx, _, found := strings.Cut(name, "_WITH_")
+	if !found || kx == "AKE" {
+		// nmap writes TLS 1.3 suites either as TLS_AES_128_GCM_SHA256 or
+		// as TLS_AKE_WITH_AES_128_GCM_SHA256.
+		if strings.HasPrefix(name, "AES_") || strings.HasPrefix(name, "CHACHA20_") || kx == "AKE" {
+			return "TLSv1.3"
+		}
+		return "unknown"
+	}
+	first, _, _ := strings.Cut(kx, "_")
+	switch first {
+	case "ECDHE", "DHE", "ECDH", "DH", "RSA", "PSK", "SRP":
+		return first
+	}
+	return "unknown"
+}
+
+// forwardSecret reports whether a key exchange returned by
+// classifyKeyExchange uses ephemeral keys.
+func forwardSecret(kx string) bool {
+	return kx == "ECDHE" || kx == "DHE" || kx == "TLSv1.3"
+}
+
+// nonForwardSecretCiphers returns the ciphers in data whose key exchange
+// does not provide forward secrecy.
+func nonForwardSecretCiphers(data CipherData) []string {
+	var nonFS []string
+	for _, cipher := range data.Ciphers {
+		if !forwardSecret(classifyKeyExchange(cipher.Name)) {
+			nonFS = append(nonFS, cipher.Name)
+		}
+	}
+	return nonFS
+}
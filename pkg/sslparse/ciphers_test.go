@@ -0,0 +1,93 @@
+package sslparse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWeakCiphers(t *testing.T) {
+	tests := []struct {
+		name    string
+		ciphers []string
+		want    []string
+	}{
+		{
+			name:    "strong only",
+			ciphers: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_AKE_WITH_CHACHA20_POLY1305_SHA256"},
+		},
+		{
+			name: "mixed",
+			ciphers: []string{
+				"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+				"TLS_RSA_WITH_RC4_128_SHA",
+				"TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+				"TLS_DHE_RSA_WITH_AES_128_CBC_SHA",
+				"TLS_RSA_WITH_DES_CBC_SHA",
+				"TLS_RSA_WITH_NULL_SHA256",
+				"TLS_RSA_EXPORT_WITH_RC2_CBC_40_MD5",
+				"TLS_RSA_WITH_RC4_128_MD5",
+			},
+			want: []string{
+				"TLS_RSA_WITH_RC4_128_SHA",
+				"TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+				"TLS_RSA_WITH_DES_CBC_SHA",
+				"TLS_RSA_WITH_NULL_SHA256",
+				"TLS_RSA_EXPORT_WITH_RC2_CBC_40_MD5",
+				"TLS_RSA_WITH_RC4_128_MD5",
+			},
+		},
+		{
+			name:    "case insensitive",
+			ciphers: []string{"tls_rsa_with_rc4_128_sha", "tls_ecdhe_rsa_with_aes_128_gcm_sha256"},
+			want:    []string{"tls_rsa_with_rc4_128_sha"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data CipherData
+			for _, name := range tt.ciphers {
+				data.Ciphers = append(data.Ciphers, Cipher{Name: name})
+			}
+			if got := weakCiphers(data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("weakCiphers = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyKeyExchange(t *testing.T) {
+	for cipher, want := range map[string]string{
+		"TLS_RSA_WITH_AES_128_CBC_SHA":           "RSA",
+		"TLS_RSA_WITH_3DES_EDE_CBC_SHA":          "RSA",
+		"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":  "ECDHE",
+		"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305": "ECDHE",
+		"TLS_DHE_RSA_WITH_AES_256_GCM_SHA384":    "DHE",
+		"TLS_ECDH_RSA_WITH_AES_128_CBC_SHA":      "ECDH",
+		"TLS_DH_anon_WITH_AES_128_CBC_SHA":       "DH",
+		"TLS_PSK_WITH_AES_128_CBC_SHA":           "PSK",
+		"SSL_RSA_WITH_RC4_128_MD5":               "RSA",
+		"tls_ecdhe_rsa_with_aes_128_gcm_sha256":  "ECDHE",
+		"TLS_AES_128_GCM_SHA256":                 "TLSv1.3",
+		"TLS_CHACHA20_POLY1305_SHA256":           "TLSv1.3",
+		"TLS_AKE_WITH_AES_256_GCM_SHA384":        "TLSv1.3",
+		"TLS_FOO_WITH_AES_128_CBC_SHA":           "unknown",
+		"garbage":                                "unknown",
+	} {
+		if got := classifyKeyExchange(cipher); got != want {
+			t.Errorf("classifyKeyExchange(%q) = %q, want %q", cipher, got, want)
+		}
+	}
+}
+
+func TestNonForwardSecretCiphers(t *testing.T) {
+	data := CipherData{Ciphers: []Cipher{
+		{Name: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"},
+		{Name: "TLS_RSA_WITH_AES_128_CBC_SHA"},
+		{Name: "TLS_DHE_RSA_WITH_AES_256_GCM_SHA384"},
+		{Name: "TLS_ECDH_RSA_WITH_AES_128_CBC_SHA"},
+	}}
+	want := []string{"TLS_RSA_WITH_AES_128_CBC_SHA", "TLS_ECDH_RSA_WITH_AES_128_CBC_SHA"}
+	if got := nonForwardSecretCiphers(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("nonForwardSecretCiphers = %q, want %q", got, want)
+	}
+}
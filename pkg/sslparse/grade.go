@@ -0,0 +1,99 @@
+package sslparse
+
+import (
+	"fmt"
+	"strings"
+)
+
+const grades = "ABCDEF"
+
+// gradePort derives an overall letter grade for the TLS configuration of a
+// port, along with the reasons it is below an A. Ports without any
+// ssl-enum-ciphers data are not graded.
+//
+// The rubric is:
+//   - start from the least strength reported by nmap (A if it is missing);
+//   - offering SSLv3, TLS 1.0 or TLS 1.1 lowers the grade by two letters;
+//   - offering any weak cipher (see weakPatterns) lowers it by three letters;
+//   - offering a version without forward secrecy lowers it by one letter;
+//   - the grade never drops below F.
+//
+// There is one reason per rule that applied, naming what triggered it and
+// ending in the number of letters it cost, e.g. "(-2)".
+func gradePort(p Port) (string, []string) {
+	offered := false
+	var deprecated, weak, nonFS []string
+	for _, v := range p.TLS.Versions() {
+		if len(v.Data.Ciphers) == 0 {
+			continue
+		}
+		offered = true
+		if v.Deprecated() {
+			deprecated = append(deprecated, v.Name)
+		}
+		weak = append(weak, v.Data.WeakCiphers...)
+		if !v.Data.ForwardSecrecy {
+			nonFS = append(nonFS, v.Name)
+		}
+	}
+	if !offered {
+		return "", nil
+	}
+
+	var reasons []string
+	grade := gradeIndex(p.TLS.Strength)
+	if grade < 0 {
+		grade = 0
+	}
+	if grade > 0 {
+		reasons = append(reasons, fmt.Sprintf("least cipher strength is %s (-%d)", strings.ToUpper(p.TLS.Strength), grade))
+	}
+	if len(deprecated) > 0 {
+		grade += 2
+		reasons = append(reasons, "offers deprecated "+strings.Join(deprecated, ", ")+" (-2)")
+	}
+	if len(weak) > 0 {
+		grade += 3
+		reasons = append(reasons, "offers weak ciphers "+strings.Join(unique(weak), ", ")+" (-3)")
+	}
+	if len(nonFS) > 0 {
+		grade++
+		reasons = append(reasons, "no forward secrecy with "+strings.Join(nonFS, ", ")+" (-1)")
+	}
+	if grade >= len(grades) {
+		grade = len(grades) - 1
+	}
+	return string(grades[grade]), reasons
+}
+
+// weakest returns the lower of two strength grades. Values that are not a
+// known grade are ignored.
+func weakest(a, b string) string {
+	if gradeIndex(b) > gradeIndex(a) {
+		return b
+	}
+	return a
+}
+
+// gradeIndex returns the position of g in grades, or -1 if g is not a
+// single known grade letter.
+func gradeIndex(g string) int {
+	if len(g) != 1 {
+		return -1
+	}
+	return strings.Index(grades, strings.ToUpper(g))
+}
+
+// unique drops repeated entries from values, keeping the first occurrence
+// of each, e.g. a weak cipher offered with several TLS versions.
+func unique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
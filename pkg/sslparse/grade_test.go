@@ -0,0 +1,127 @@
+package sslparse
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	nmap "github.com/Ullaakut/nmap/v3"
+)
+
+// sslPort parses a port on which ssl-enum-ciphers printed output.
+func sslPort(output string) Port {
+	return parsePort(nmap.Port{
+		ID:       443,
+		Protocol: "tcp",
+		State:    nmap.State{State: "open"},
+		Scripts:  []nmap.Script{{ID: sslEnumCiphers, Output: output}},
+	})
+}
+
+const tls13Only = `
+  TLSv1.3: 
+    ciphers: 
+      TLS_AKE_WITH_AES_128_GCM_SHA256 (ecdh_x25519) - A
+      TLS_AKE_WITH_AES_256_GCM_SHA384 (ecdh_x25519) - A
+    cipher preference: server
+  least strength: A`
+
+const tls10RC4 = `
+  TLSv1.0: 
+    ciphers: 
+      TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA (secp256r1) - A
+      TLS_RSA_WITH_RC4_128_SHA (rsa 2048) - C
+    cipher preference: server
+  least strength: C`
+
+const ssl3 = `
+  SSLv3: 
+    ciphers: 
+      TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA (secp256r1) - A
+    cipher preference: server
+  TLSv1.2: 
+    ciphers: 
+      TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 (secp256r1) - A
+    cipher preference: server
+  least strength: A`
+
+const tls12RSA = `
+  TLSv1.2: 
+    ciphers: 
+      TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 (secp256r1) - A
+      TLS_RSA_WITH_AES_128_GCM_SHA256 (rsa 2048) - A
+    cipher preference: server
+  least strength: A`
+
+const tls12ECDHE = `
+  TLSv1.2: 
+    ciphers: 
+      TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 (secp256r1) - A
+      TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384 (secp256r1) - A
+    cipher preference: server
+  least strength: A`
+
+func TestGradePort(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{"TLS 1.3 only", tls13Only, "A"},
+		{"TLS 1.0 with RC4", tls10RC4, "F"},
+		{"TLS 1.2 with ECDHE only", tls12ECDHE, "A"},
+		{"TLS 1.2 with an RSA key exchange", tls12RSA, "B"},
+		{"SSLv3 next to TLS 1.2", ssl3, "C"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port := sslPort(tt.output)
+			if port.Grade != tt.want {
+				t.Errorf("grade = %q (%q), want %q", port.Grade, port.GradeReasons, tt.want)
+			}
+			if tt.want == "A" && len(port.GradeReasons) > 0 {
+				t.Errorf("grade A with reasons %q", port.GradeReasons)
+			}
+		})
+	}
+
+	if grade, reasons := gradePort(Port{}); grade != "" || reasons != nil {
+		t.Errorf("port without TLS data graded %q (%q), want ungraded", grade, reasons)
+	}
+}
+
+func TestGradeReasons(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{"TLS 1.0 with RC4", tls10RC4, []string{
+			"least cipher strength is C (-2)",
+			"offers deprecated TLSv1.0 (-2)",
+			"offers weak ciphers TLS_RSA_WITH_RC4_128_SHA (-3)",
+			"no forward secrecy with TLSv1.0 (-1)",
+		}},
+		{"TLS 1.2 with an RSA key exchange", tls12RSA, []string{
+			"no forward secrecy with TLSv1.2 (-1)",
+		}},
+		{"TLS 1.3 only", tls13Only, nil},
+		{"SSLv3 next to TLS 1.2", ssl3, []string{
+			"offers deprecated SSLv3 (-2)",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sslPort(tt.output).GradeReasons; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reasons = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Every version without forward secrecy is named.
+	port := sslPort(strings.TrimSuffix(tls10RC4, "  least strength: C") + strings.TrimPrefix(tls12RSA, "\n"))
+	want := "no forward secrecy with TLSv1.0, TLSv1.2 (-1)"
+	if reasons := port.GradeReasons; len(reasons) == 0 || reasons[len(reasons)-1] != want {
+		t.Errorf("reasons = %q, want the last one to be %q", reasons, want)
+	}
+}
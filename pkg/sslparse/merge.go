@@ -0,0 +1,102 @@
+package sslparse
+
+import "reflect"
+
+// MergeHost folds other, a second entry for the same IP, into host. Ports
+// are combined; for a port both entries report, the one with more detail
+// is kept, see portDetail, and host's on a tie. When both entries carry
+// differing ssl-enum-ciphers results for a port, e.g. two virtual hosts
+// scanned with their own SNI, other's port is returned in dropped so the
+// caller can report it. Hostnames, addresses and host scripts are combined
+// without duplicates. A host is up if either entry is, and the remaining
+// details are taken from other only where host has none.
+func MergeHost(host *HostInfo, other HostInfo) (dropped []Port) {
+	if other.Status == "up" {
+		host.Status = other.Status
+	}
+
+	seen := make(map[string]bool, len(host.Hostnames))
+	for _, name := range host.Hostnames {
+		seen[name] = true
+	}
+	for _, name := range other.Hostnames {
+		if !seen[name] {
+			seen[name] = true
+			host.Hostnames = append(host.Hostnames, name)
+		}
+	}
+
+	addrs := make(map[AddressInfo]bool, len(host.Addresses))
+	for _, addr := range host.Addresses {
+		addrs[addr] = true
+	}
+	for _, addr := range other.Addresses {
+		if !addrs[addr] {
+			addrs[addr] = true
+			host.Addresses = append(host.Addresses, addr)
+		}
+	}
+
+	type portKey struct {
+		id       uint16
+		protocol string
+	}
+	ports := make(map[portKey]int, len(host.Ports))
+	for i, port := range host.Ports {
+		ports[portKey{port.ID, port.Protocol}] = i
+	}
+	for _, port := range other.Ports {
+		key := portKey{port.ID, port.Protocol}
+		i, ok := ports[key]
+		if !ok {
+			ports[key] = len(host.Ports)
+			host.Ports = append(host.Ports, port)
+			continue
+		}
+		kept := &host.Ports[i]
+		if portDetail(port) > portDetail(*kept) {
+			*kept, port = port, *kept
+		}
+		if hasTLS(*kept) && hasTLS(port) && !reflect.DeepEqual(kept.TLS, port.TLS) {
+			dropped = append(dropped, port)
+		}
+	}
+
+	for id, output := range other.HostScripts {
+		if _, ok := host.HostScripts[id]; ok {
+			continue
+		}
+		if host.HostScripts == nil {
+			host.HostScripts = make(map[string]string)
+		}
+		host.HostScripts[id] = output
+	}
+
+	if host.MAC == "" {
+		host.MAC, host.Vendor = other.MAC, other.Vendor
+	}
+	if host.LatencySeconds == 0 {
+		host.LatencySeconds = other.LatencySeconds
+	}
+	if host.OS == nil {
+		host.OS = other.OS
+	}
+	return dropped
+}
+
+// portDetail ranks how much a port entry tells about the port: TLS data
+// beats an open state without it, which beats any other state.
+func portDetail(p Port) int {
+	switch {
+	case hasTLS(p):
+		return 2
+	case p.State == "open":
+		return 1
+	}
+	return 0
+}
+
+// hasTLS reports whether ssl-enum-ciphers found any offered version on p.
+func hasTLS(p Port) bool {
+	return len(p.TLS.Offered()) > 0
+}
@@ -0,0 +1,371 @@
+package sslparse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	nmap "github.com/Ullaakut/nmap/v3"
+)
+
+// ParseRun converts an nmap run into a Hosts report. A host whose output
+// cannot be parsed is recorded in Hosts.Errors and the remaining hosts are
+// still processed. Hosts that appear more than once in the run, e.g. from
+// several batches, are merged into one entry by IP, see MergeHost.
+func ParseRun(result *nmap.Run) Hosts {
+	// Hosts is never nil, so a scan without hosts is reported as an empty
+	// list rather than null.
+	hosts := Hosts{Meta: RunMeta(result), Note: OfferedNote, Hosts: []HostInfo{}}
+	index := make(map[string]int)
+	for _, host := range result.Hosts {
+		info, errs, ok := ParseHostChecked(host)
+		hosts.Errors = append(hosts.Errors, errs...)
+		if !ok {
+			continue
+		}
+		if i, seen := index[info.IP]; seen && info.IP != "" {
+			for _, port := range MergeHost(&hosts.Hosts[i], info) {
+				hosts.Errors = append(hosts.Errors, HostError{
+					IP:      info.IP,
+					Message: fmt.Sprintf("port %d/%s: dropped differing %s results of a duplicate host entry", port.ID, port.Protocol, sslEnumCiphers),
+				})
+			}
+			continue
+		}
+		index[info.IP] = len(hosts.Hosts)
+		hosts.Hosts = append(hosts.Hosts, info)
+	}
+	hosts.Summary = Summarize(hosts.Hosts)
+
+	return hosts
+}
+
+// ParseHostChecked is ParseHost for callers that process a run one host at
+// a time but still want the errors ParseRun collects. ok is false when the
+// host could not be parsed at all, in which case info must be dropped.
+func ParseHostChecked(host nmap.Host) (info HostInfo, errs []HostError, ok bool) {
+	info, err := parseHostSafely(host)
+	if err != nil {
+		return info, []HostError{{IP: hostIP(host), Message: err.Error()}}, false
+	}
+	for _, port := range info.Ports {
+		if port.TLS.Error != "" {
+			errs = append(errs, HostError{
+				IP:      info.IP,
+				Message: fmt.Sprintf("port %d/%s: %s: %s", port.ID, port.Protocol, sslEnumCiphers, port.TLS.Error),
+			})
+		}
+		if port.Certificate != nil && port.Certificate.Error != "" {
+			errs = append(errs, HostError{
+				IP:      info.IP,
+				Message: fmt.Sprintf("port %d/%s: %s: %s", port.ID, port.Protocol, sslCert, port.Certificate.Error),
+			})
+		}
+	}
+	return info, errs, true
+}
+
+// parseHostSafely runs ParseHost, turning a panic on unexpected input into
+// an error so that one bad host does not abort the whole report.
+func parseHostSafely(host nmap.Host) (info HostInfo, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("parsing host: %v", r)
+		}
+	}()
+	return ParseHost(host), nil
+}
+
+// ParseHost converts a single nmap host into a HostInfo, so callers can
+// process a run one host at a time.
+func ParseHost(host nmap.Host) HostInfo {
+	// Hosts discovered inside a CIDR range may have no ports at all;
+	// keep them in the report with an empty (not null) port list.
+	hostInfo := HostInfo{Ports: []Port{}}
+	hostInfo.IP = hostIP(host)
+	hostInfo.Status = host.Status.State
+	for _, addr := range host.Addresses {
+		hostInfo.Addresses = append(hostInfo.Addresses, AddressInfo{Addr: addr.Addr, Type: addr.AddrType})
+		if addr.AddrType == "mac" && hostInfo.MAC == "" {
+			hostInfo.MAC = addr.Addr
+			hostInfo.Vendor = addr.Vendor
+		}
+	}
+	for _, hostname := range host.Hostnames {
+		hostInfo.Hostnames = append(hostInfo.Hostnames, hostname.Name)
+	}
+	// nmap reports the smoothed round-trip time in microseconds.
+	if srtt, err := strconv.ParseFloat(host.Times.SRTT, 64); err == nil {
+		hostInfo.LatencySeconds = srtt / 1e6
+	}
+	hostInfo.OS = bestOSMatch(host.OS.Matches)
+	for _, script := range host.HostScripts {
+		if hostInfo.HostScripts == nil {
+			hostInfo.HostScripts = make(map[string]string)
+		}
+		hostInfo.HostScripts[script.ID] = script.Output
+	}
+
+	for _, port := range host.Ports {
+		hostInfo.Ports = append(hostInfo.Ports, parsePort(port))
+	}
+	return hostInfo
+}
+
+func parsePort(port nmap.Port) Port {
+	p := Port{
+		ID:          port.ID,
+		Protocol:    port.Protocol,
+		Service:     port.Service.Name,
+		State:       port.State.State,
+		StateReason: port.State.Reason,
+
+		Product:   port.Service.Product,
+		Version:   port.Service.Version,
+		ExtraInfo: port.Service.ExtraInfo,
+		Tunnel:    port.Service.Tunnel,
+	}
+	for _, script := range port.Scripts {
+		if p.RawScriptOutput == nil {
+			p.RawScriptOutput = make(map[string]string)
+		}
+		p.RawScriptOutput[script.ID] = script.Output
+		if script.ID != sslEnumCiphers {
+			if p.Scripts == nil {
+				p.Scripts = make(map[string]string)
+			}
+			p.Scripts[script.ID] = script.Output
+			if script.ID == sslCert {
+				cert := parseCertOutput(script.Output)
+				p.Certificate = &cert
+			}
+			continue
+		}
+		p.TLS.Raw = script.Output
+		tlsVersions, strength := ParseScriptOutput(script.Output)
+		if len(tlsVersions) == 0 {
+			p.TLS.Error = scriptError(script.Output)
+			continue
+		}
+		mergeTLS(&p.TLS, tlsVersions, strength)
+	}
+	p.Grade, p.GradeReasons = gradePort(p)
+	p.STARTTLS = usesSTARTTLS(p)
+	return p
+}
+
+// starttlsServices are the nmap service names of plain protocols that
+// ssl-enum-ciphers upgrades with STARTTLS (or its protocol equivalent).
+var starttlsServices = map[string]bool{
+	"ftp": true, "imap": true, "ldap": true, "lmtp": true, "ms-sql-s": true,
+	"mysql": true, "nntp": true, "pop3": true, "postgresql": true, "smtp": true,
+	"submission": true, "vnc": true, "xmpp-client": true, "xmpp-server": true,
+}
+
+// usesSTARTTLS infers whether the TLS data of p was obtained through
+// STARTTLS: TLS was offered, nmap did not find an SSL tunnel, and the
+// service is a plain protocol with a STARTTLS upgrade.
+func usesSTARTTLS(p Port) bool {
+	return len(p.TLS.Offered()) > 0 && p.Tunnel != "ssl" && starttlsServices[p.Service]
+}
+
+// RunMeta returns the metadata of an nmap run.
+func RunMeta(result *nmap.Run) *ScanMeta {
+	return &ScanMeta{
+		StartedAt:      time.Time(result.Start),
+		FinishedAt:     time.Time(result.Stats.Finished.Time),
+		ElapsedSeconds: float64(result.Stats.Finished.Elapsed),
+		NmapVersion:    result.Version,
+		Args:           result.Args,
+	}
+}
+
+// scriptError describes ssl-enum-ciphers output that contains no TLS
+// version sections: the message the script printed, or a note that it
+// printed nothing.
+func scriptError(output string) string {
+	if msg := strings.TrimSpace(output); msg != "" {
+		return msg
+	}
+	return "empty script output"
+}
+
+// mergeTLS copies the versions found in tlsVersions into tls, leaving
+// versions that were not reported untouched.
+func mergeTLS(tls *TLSVersions, tlsVersions map[string]CipherData, strength string) {
+	for version, dst := range map[string]*CipherData{
+		"SSLv3":   &tls.SSL3,
+		"TLSv1.0": &tls.TLS10,
+		"TLSv1.1": &tls.TLS11,
+		"TLSv1.2": &tls.TLS12,
+		"TLSv1.3": &tls.TLS13,
+	} {
+		if data, ok := tlsVersions[version]; ok {
+			*dst = data
+		}
+	}
+	if strength != "" {
+		tls.Strength = strength
+	}
+}
+
+// bestOSMatch returns the match with the highest accuracy, preferring the
+// first one reported on ties, or nil when there are no matches.
+func bestOSMatch(matches []nmap.OSMatch) *OSMatch {
+	var best *OSMatch
+	for _, m := range matches {
+		if best == nil || m.Accuracy > best.Accuracy {
+			best = &OSMatch{Name: m.Name, Accuracy: m.Accuracy}
+		}
+	}
+	return best
+}
+
+// hostIP returns the first IPv4 address of host, falling back to the first
+// IPv6 address. Some down hosts are reported with hostnames but no
+// addresses, in which case the first hostname is used.
+func hostIP(host nmap.Host) string {
+	for _, addrType := range []string{"ipv4", "ipv6"} {
+		for _, addr := range host.Addresses {
+			if addr.AddrType == addrType {
+				return addr.String()
+			}
+		}
+	}
+	if len(host.Hostnames) > 0 {
+		return host.Hostnames[0].String()
+	}
+	return ""
+}
+
+// Anchored patterns for the structural lines of ssl-enum-ciphers output.
+// Anything else inside a section is a detail line belonging to the most
+// recent sub-section header, so cipher names that happen to contain words
+// like "ciphers" or "warnings" are not mistaken for headers.
+var (
+	versionHeader     = regexp.MustCompile(`^\s*((?:SSL|TLS)v[\d.]+):\s*$`)
+	sectionHeader     = regexp.MustCompile(`^\s*(ciphers|compressors|warnings):\s*$`)
+	preferenceLine    = regexp.MustCompile(`^\s*cipher preference:(.*)$`)
+	leastStrengthLine = regexp.MustCompile(`^\s*least strength:\s*(\S+)`)
+)
+
+// ParseScriptOutput parses the output of the ssl-enum-ciphers script into
+// per-version cipher data keyed by protocol name (e.g. "TLSv1.2"), and
+// returns the overall least strength reported by the script.
+func ParseScriptOutput(output string) (map[string]CipherData, string) {
+	tlsVersions := make(map[string]CipherData)
+	var strength string
+	// Output captured on Windows or through some pipes uses CRLF line
+	// endings; normalise them so no stray \r ends up in parsed values.
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	output = strings.ReplaceAll(output, "\r", "\n")
+	lines := strings.Split(output, "\n")
+	var key string
+	var currentTLSVersion string
+	var sectionIndent int
+	var keyIndent int
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if m := versionHeader.FindStringSubmatch(line); m != nil {
+			// Start of a new TLS version section
+			currentTLSVersion = m[1]
+			sectionIndent = indentation(line)
+			tlsVersions[currentTLSVersion] = CipherData{}
+			key = "" // Reset key when starting a new section
+			continue
+		}
+		if m := leastStrengthLine.FindStringSubmatch(line); m != nil {
+			// A least strength line nested inside a version section
+			// applies to that version only; otherwise it covers the port.
+			if currentTLSVersion != "" && indentation(line) > sectionIndent {
+				data := tlsVersions[currentTLSVersion]
+				data.LeastStrength = m[1]
+				tlsVersions[currentTLSVersion] = data
+			} else {
+				strength = m[1]
+			}
+			continue
+		}
+		if currentTLSVersion == "" {
+			continue
+		}
+
+		data := tlsVersions[currentTLSVersion]
+		if m := preferenceLine.FindStringSubmatch(line); m != nil {
+			data.Preference = strings.TrimSpace(m[1])
+			key = ""
+		} else if m := sectionHeader.FindStringSubmatch(line); m != nil {
+			// Detect the key for the current section
+			key = m[1]
+			keyIndent = indentation(line)
+		} else if trimmed == "" || indentation(line) <= keyIndent {
+			// A blank line or one that is not nested below the
+			// sub-section header ends the sub-section, so it is not
+			// appended to the previous list.
+			key = ""
+		} else {
+			// Append line to the corresponding field in CipherData
+			switch key {
+			case "ciphers":
+				cipher := parseCipher(trimmed)
+				data.Ciphers = append(data.Ciphers, cipher)
+			case "compressors":
+				if trimmed != "NULL" {
+					data.Compressors = append(data.Compressors, trimmed)
+				}
+			case "warnings":
+				data.Warnings = append(data.Warnings, trimmed)
+			}
+		}
+		tlsVersions[currentTLSVersion] = data
+	}
+
+	var weakestVersion string
+	for version, data := range tlsVersions {
+		data.CipherCount = len(data.Ciphers)
+		data.WeakCiphers = weakCiphers(data)
+		data.NonFSCiphers = nonForwardSecretCiphers(data)
+		data.ForwardSecrecy = len(data.Ciphers) > 0 && len(data.NonFSCiphers) == 0
+		if data.LeastStrength == "" {
+			for _, cipher := range data.Ciphers {
+				data.LeastStrength = weakest(data.LeastStrength, cipher.Strength)
+			}
+		}
+		weakestVersion = weakest(weakestVersion, data.LeastStrength)
+		tlsVersions[version] = data
+	}
+	if strength == "" {
+		strength = weakestVersion
+	}
+
+	return tlsVersions, strength
+}
+
+// parseCipher splits a cipher line such as
+// "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 (secp256r1) - A" into its name,
+// key information and strength grade. Missing parts are left empty.
+func parseCipher(line string) Cipher {
+	var cipher Cipher
+	if i := strings.LastIndex(line, " - "); i >= 0 {
+		if grade := strings.TrimSpace(line[i+3:]); grade != "" && !strings.Contains(grade, " ") {
+			cipher.Strength = grade
+			line = line[:i]
+		}
+	}
+	line = strings.TrimSpace(line)
+	if i := strings.Index(line, " ("); i >= 0 && strings.HasSuffix(line, ")") {
+		cipher.KeyInfo = line[i+2 : len(line)-1]
+		line = line[:i]
+	}
+	cipher.Name = line
+	return cipher
+}
+
+// indentation returns the number of leading spaces and tabs in line.
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
@@ -0,0 +1,726 @@
+package sslparse
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	nmap "github.com/Ullaakut/nmap/v3"
+)
+
+// ipv4Host returns an up host with a single IPv4 address and the given
+// ports.
+func ipv4Host(ip string, ports ...nmap.Port) nmap.Host {
+	return nmap.Host{
+		Status:    nmap.Status{State: "up"},
+		Addresses: []nmap.Address{{Addr: ip, AddrType: "ipv4"}},
+		Ports:     ports,
+	}
+}
+
+// readFixture returns the contents of testdata/name.
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// cipherNames returns the names of the ciphers in data.
+func cipherNames(data CipherData) []string {
+	var names []string
+	for _, cipher := range data.Ciphers {
+		names = append(names, cipher.Name)
+	}
+	return names
+}
+
+func TestParseRunKeepsHostsWithoutPorts(t *testing.T) {
+	// A scan of 10.0.0.0/30: every live host in the range is reported,
+	// but only one of them has an open port.
+	run := &nmap.Run{Hosts: []nmap.Host{
+		ipv4Host("10.0.0.1"),
+		ipv4Host("10.0.0.2", nmap.Port{ID: 443, Protocol: "tcp", State: nmap.State{State: "open"}}),
+		ipv4Host("10.0.0.3"),
+	}}
+
+	hosts := ParseRun(run)
+	if len(hosts.Hosts) != 3 {
+		t.Fatalf("got %d hosts, want 3", len(hosts.Hosts))
+	}
+	for i, want := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		if got := hosts.Hosts[i].IP; got != want {
+			t.Errorf("host %d: IP = %q, want %q", i, got, want)
+		}
+	}
+	for _, i := range []int{0, 2} {
+		if ports := hosts.Hosts[i].Ports; !reflect.DeepEqual(ports, []Port{}) {
+			t.Errorf("host %s: Ports = %#v, want an empty, non-nil slice", hosts.Hosts[i].IP, ports)
+		}
+	}
+	if n := len(hosts.Hosts[1].Ports); n != 1 {
+		t.Errorf("host 10.0.0.2: got %d ports, want 1", n)
+	}
+}
+
+func TestParseHostWithoutAddresses(t *testing.T) {
+	// Some down hosts are reported with hostnames but no address at all.
+	host := nmap.Host{
+		Status:    nmap.Status{State: "down"},
+		Addresses: nil,
+		Hostnames: []nmap.Hostname{{Name: "gone.example.com"}, {Name: "alias.example.com"}},
+	}
+
+	info, _, ok := ParseHostChecked(host)
+	if !ok {
+		t.Fatal("ParseHostChecked reported the host as unparseable")
+	}
+	if info.IP != "gone.example.com" {
+		t.Errorf("IP = %q, want the first hostname", info.IP)
+	}
+
+	if ip := ParseHost(nmap.Host{}).IP; ip != "" {
+		t.Errorf("host without addresses or hostnames: IP = %q, want empty", ip)
+	}
+}
+
+func TestParsePortWithSeveralScripts(t *testing.T) {
+	// ssl-enum-ciphers is not always the only script on a port, and
+	// the other scripts must not replace its data (or the other way round).
+	port := parsePort(nmap.Port{
+		ID:       443,
+		Protocol: "tcp",
+		State:    nmap.State{State: "open"},
+		Scripts: []nmap.Script{
+			{ID: "ssl-enum-ciphers", Output: readFixture(t, "ssl_enum_ciphers.txt")},
+			{ID: "http-title", Output: "Example Domain"},
+		},
+	})
+
+	if len(port.TLS.TLS12.Ciphers) != 2 {
+		t.Errorf("TLSv1.2 ciphers = %v, want 2", port.TLS.TLS12.Ciphers)
+	}
+	if port.TLS.Strength != "C" {
+		t.Errorf("least strength = %q, want %q", port.TLS.Strength, "C")
+	}
+	if got := port.Scripts["http-title"]; got != "Example Domain" {
+		t.Errorf("Scripts[http-title] = %q, want %q", got, "Example Domain")
+	}
+	if _, ok := port.Scripts["ssl-enum-ciphers"]; ok {
+		t.Error("ssl-enum-ciphers output is duplicated in Scripts")
+	}
+}
+
+func TestParseScriptOutputCipherPreference(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"    cipher preference: server", "server"},
+		{"    cipher preference: client", "client"},
+		{"    cipher preference: indeterminate", "indeterminate"},
+		{"    cipher preference:", ""},
+	}
+	for _, tt := range tests {
+		output := "\n  TLSv1.2: \n    ciphers: \n      TLS_RSA_WITH_AES_128_GCM_SHA256 (rsa 2048) - A\n" + tt.line + "\n"
+		versions, _ := ParseScriptOutput(output)
+		if got := versions["TLSv1.2"].Preference; got != tt.want {
+			t.Errorf("%q: Preference = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseCipher(t *testing.T) {
+	tests := []struct {
+		line string
+		want Cipher
+	}{
+		{
+			"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 (secp256r1) - A",
+			Cipher{Name: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", KeyInfo: "secp256r1", Strength: "A"},
+		},
+		{
+			"TLS_RSA_WITH_3DES_EDE_CBC_SHA (rsa 2048) - C",
+			Cipher{Name: "TLS_RSA_WITH_3DES_EDE_CBC_SHA", KeyInfo: "rsa 2048", Strength: "C"},
+		},
+		{
+			"TLS_RSA_WITH_AES_128_CBC_SHA (rsa 2048)",
+			Cipher{Name: "TLS_RSA_WITH_AES_128_CBC_SHA", KeyInfo: "rsa 2048"},
+		},
+		{
+			"TLS_RSA_WITH_AES_128_CBC_SHA",
+			Cipher{Name: "TLS_RSA_WITH_AES_128_CBC_SHA"},
+		},
+	}
+	for _, tt := range tests {
+		if got := parseCipher(tt.line); got != tt.want {
+			t.Errorf("parseCipher(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseScriptOutputCRLFAndTabs(t *testing.T) {
+	output := readFixture(t, "ssl_enum_ciphers.txt")
+	want, wantStrength := ParseScriptOutput(output)
+
+	// The same output as captured on Windows: CRLF line endings and a
+	// tab for every two spaces of indentation.
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		lines = append(lines, strings.Repeat("\t", (len(line)-len(trimmed))/2)+trimmed)
+	}
+	got, strength := ParseScriptOutput(strings.Join(lines, "\r\n"))
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CRLF output parsed as\n%+v\nwant\n%+v", got, want)
+	}
+	if strength != wantStrength {
+		t.Errorf("least strength = %q, want %q", strength, wantStrength)
+	}
+	for _, cipher := range cipherNames(got["TLSv1.0"]) {
+		if strings.ContainsAny(cipher, "\r\t ") {
+			t.Errorf("cipher name %q contains whitespace", cipher)
+		}
+	}
+	if len(got["TLSv1.0"].Ciphers) != 3 {
+		t.Errorf("TLSv1.0 ciphers = %q, want 3", cipherNames(got["TLSv1.0"]))
+	}
+}
+
+func TestParsePortServiceInfo(t *testing.T) {
+	port := parsePort(nmap.Port{
+		ID:       443,
+		Protocol: "tcp",
+		State:    nmap.State{State: "open"},
+		Service: nmap.Service{
+			Name:      "https",
+			Product:   "nginx",
+			Version:   "1.25.3",
+			ExtraInfo: "Ubuntu",
+		},
+	})
+	if port.Service != "https" || port.Product != "nginx" || port.Version != "1.25.3" || port.ExtraInfo != "Ubuntu" {
+		t.Errorf("service info = %q %q %q %q, want https nginx 1.25.3 Ubuntu",
+			port.Service, port.Product, port.Version, port.ExtraInfo)
+	}
+
+	// Without -sV only the service name is known, and the empty fields
+	// are left out of the JSON.
+	data, err := json.Marshal(parsePort(nmap.Port{ID: 443, Service: nmap.Service{Name: "https"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"product", "version", "extra_info"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("empty %s is present in %s", key, data)
+		}
+	}
+}
+
+func TestParsePortTunnel(t *testing.T) {
+	port := parsePort(nmap.Port{ID: 8443, Service: nmap.Service{Name: "http", Tunnel: "ssl"}})
+	if port.Tunnel != "ssl" {
+		t.Errorf("Tunnel = %q, want ssl", port.Tunnel)
+	}
+	data, err := json.Marshal(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"tunnel":"ssl"`) {
+		t.Errorf("JSON %s has no tunnel", data)
+	}
+
+	// Without a tunnel the key is left out.
+	data, err = json.Marshal(parsePort(nmap.Port{ID: 80, Service: nmap.Service{Name: "http"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"tunnel"`) {
+		t.Errorf("JSON %s has a tunnel for a plain port", data)
+	}
+}
+
+func TestParseRunHostStatus(t *testing.T) {
+	down := ipv4Host("10.0.0.2")
+	down.Status.State = "down"
+	run := &nmap.Run{Hosts: []nmap.Host{ipv4Host("10.0.0.1"), down}}
+
+	hosts := ParseRun(run)
+	var got []string
+	for _, host := range hosts.Hosts {
+		got = append(got, host.IP+" "+host.Status)
+	}
+	want := []string{"10.0.0.1 up", "10.0.0.2 down"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hosts = %q, want %q", got, want)
+	}
+}
+
+func TestParsePortKeepsOtherScripts(t *testing.T) {
+	port := parsePort(nmap.Port{
+		ID:       443,
+		Protocol: "tcp",
+		Scripts:  []nmap.Script{{ID: "http-server-header", Output: "nginx/1.25.3"}},
+	})
+	want := map[string]string{"http-server-header": "nginx/1.25.3"}
+	if !reflect.DeepEqual(port.Scripts, want) {
+		t.Errorf("Scripts = %q, want %q", port.Scripts, want)
+	}
+	if port.TLS.Offered() != nil || port.TLS.Error != "" {
+		t.Errorf("TLS = %+v, want no ssl-enum-ciphers data", port.TLS)
+	}
+}
+
+func TestParseRunKeepsUDPPorts(t *testing.T) {
+	run := &nmap.Run{Hosts: []nmap.Host{ipv4Host("10.0.0.1",
+		nmap.Port{ID: 443, Protocol: "tcp", State: nmap.State{State: "open"}},
+		nmap.Port{ID: 443, Protocol: "udp", State: nmap.State{State: "open|filtered"}, Service: nmap.Service{Name: "https"}},
+	)}}
+
+	ports := ParseRun(run).Hosts[0].Ports
+	if len(ports) != 2 {
+		t.Fatalf("got %d ports, want 2", len(ports))
+	}
+	if ports[1].Protocol != "udp" || ports[1].ID != 443 || ports[1].State != "open|filtered" {
+		t.Errorf("UDP port = %d/%s %s, want 443/udp open|filtered", ports[1].ID, ports[1].Protocol, ports[1].State)
+	}
+}
+
+func TestParseScriptOutputPerVersionStrength(t *testing.T) {
+	output := `
+  TLSv1.1: 
+    ciphers: 
+      TLS_RSA_WITH_AES_128_CBC_SHA (rsa 2048) - A
+      TLS_RSA_WITH_3DES_EDE_CBC_SHA (rsa 2048) - C
+    cipher preference: server
+    least strength: C
+  TLSv1.2: 
+    ciphers: 
+      TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 (secp256r1) - A
+      TLS_RSA_WITH_AES_256_CBC_SHA (rsa 1024) - B
+    cipher preference: server
+    least strength: B
+  TLSv1.3: 
+    ciphers: 
+      TLS_AKE_WITH_AES_128_GCM_SHA256 (ecdh_x25519) - A
+    cipher preference: server
+`
+	versions, strength := ParseScriptOutput(output)
+	for version, want := range map[string]string{
+		"TLSv1.1": "C",
+		"TLSv1.2": "B",
+		// Without a least strength line of its own, a version gets the
+		// weakest grade of its ciphers.
+		"TLSv1.3": "A",
+	} {
+		if got := versions[version].LeastStrength; got != want {
+			t.Errorf("%s least strength = %q, want %q", version, got, want)
+		}
+	}
+	if strength != "C" {
+		t.Errorf("overall least strength = %q, want the weakest version's C", strength)
+	}
+}
+
+func TestRunMeta(t *testing.T) {
+	start := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+	run := &nmap.Run{
+		Args:    "nmap -p 443 --script ssl-enum-ciphers -oX - example.com",
+		Version: "7.94",
+		Start:   nmap.Timestamp(start),
+	}
+	run.Stats.Finished.Time = nmap.Timestamp(start.Add(12 * time.Second))
+	run.Stats.Finished.Elapsed = 12
+
+	meta := ParseRun(run).Meta
+	want := &ScanMeta{
+		StartedAt:      start,
+		FinishedAt:     start.Add(12 * time.Second),
+		ElapsedSeconds: 12,
+		NmapVersion:    "7.94",
+		Args:           "nmap -p 443 --script ssl-enum-ciphers -oX - example.com",
+	}
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("Meta = %+v, want %+v", meta, want)
+	}
+}
+
+func TestParseHostAddresses(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses []nmap.Address
+		wantIP    string
+		wantMAC   string
+		vendor    string
+	}{
+		{
+			name:      "IPv6 only",
+			addresses: []nmap.Address{{Addr: "2001:db8::1", AddrType: "ipv6"}},
+			wantIP:    "2001:db8::1",
+		},
+		{
+			name: "dual stack prefers IPv4",
+			addresses: []nmap.Address{
+				{Addr: "2001:db8::1", AddrType: "ipv6"},
+				{Addr: "192.0.2.1", AddrType: "ipv4"},
+			},
+			wantIP: "192.0.2.1",
+		},
+		{
+			name: "IPv4 and MAC",
+			addresses: []nmap.Address{
+				{Addr: "00:11:22:33:44:55", AddrType: "mac", Vendor: "Acme"},
+				{Addr: "10.0.0.2", AddrType: "ipv4"},
+			},
+			wantIP:  "10.0.0.2",
+			wantMAC: "00:11:22:33:44:55",
+			vendor:  "Acme",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := ParseHost(nmap.Host{Addresses: tt.addresses})
+			if info.IP != tt.wantIP {
+				t.Errorf("IP = %q, want %q", info.IP, tt.wantIP)
+			}
+			var want []AddressInfo
+			for _, addr := range tt.addresses {
+				want = append(want, AddressInfo{Addr: addr.Addr, Type: addr.AddrType})
+			}
+			if !reflect.DeepEqual(info.Addresses, want) {
+				t.Errorf("Addresses = %+v, want %+v", info.Addresses, want)
+			}
+			if info.MAC != tt.wantMAC || info.Vendor != tt.vendor {
+				t.Errorf("MAC, Vendor = %q, %q, want %q, %q", info.MAC, info.Vendor, tt.wantMAC, tt.vendor)
+			}
+		})
+	}
+}
+
+func TestParseScriptOutputHeaderWordsInCipherNames(t *testing.T) {
+	// Made-up cipher names containing the sub-section header words must
+	// stay in the cipher list instead of starting a new sub-section.
+	output := `
+  TLSv1.2: 
+    ciphers: 
+      TLS_CIPHERS_WITH_AES_128_GCM_SHA256 (secp256r1) - A
+      warnings_TLS_RSA_WITH_AES_256_CBC_SHA (rsa 2048) - A
+      TLS_ECDHE_RSA_WITH_ciphers: (secp256r1) - A
+    warnings: 
+      ciphers: is not a header here
+`
+	versions, _ := ParseScriptOutput(output)
+	data := versions["TLSv1.2"]
+	wantCiphers := []string{
+		"TLS_CIPHERS_WITH_AES_128_GCM_SHA256",
+		"warnings_TLS_RSA_WITH_AES_256_CBC_SHA",
+		"TLS_ECDHE_RSA_WITH_ciphers:",
+	}
+	if got := cipherNames(data); !reflect.DeepEqual(got, wantCiphers) {
+		t.Errorf("ciphers = %q, want %q", got, wantCiphers)
+	}
+	if want := []string{"ciphers: is not a header here"}; !reflect.DeepEqual(data.Warnings, want) {
+		t.Errorf("warnings = %q, want %q", data.Warnings, want)
+	}
+}
+
+func TestParseHostLatency(t *testing.T) {
+	host := ipv4Host("10.0.0.1")
+	host.Times = nmap.Times{SRTT: "12000"}
+	if got := ParseHost(host).LatencySeconds; got != 0.012 {
+		t.Errorf("LatencySeconds = %v, want 0.012 for an SRTT of 12000µs", got)
+	}
+	if got := ParseHost(ipv4Host("10.0.0.1")).LatencySeconds; got != 0 {
+		t.Errorf("LatencySeconds without SRTT = %v, want 0", got)
+	}
+}
+
+func TestRawScriptOutputRoundTrip(t *testing.T) {
+	// Leading newlines, trailing spaces and odd characters must all
+	// survive, since the raw output is meant for debugging the parser.
+	raw := map[string]string{
+		"ssl-enum-ciphers": readFixture(t, "ssl_enum_ciphers.txt"),
+		"http-title":       "  <Example> & \"Title\"\t\r\n",
+	}
+	port := parsePort(nmap.Port{ID: 443, Protocol: "tcp", Scripts: []nmap.Script{
+		{ID: "ssl-enum-ciphers", Output: raw["ssl-enum-ciphers"]},
+		{ID: "http-title", Output: raw["http-title"]},
+	}})
+
+	data, err := json.Marshal(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Port
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.RawScriptOutput, raw) {
+		t.Errorf("RawScriptOutput after a JSON round trip =\n%q\nwant\n%q", decoded.RawScriptOutput, raw)
+	}
+}
+
+func TestParsePortScriptError(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"\n  No supported ciphers found\n", "No supported ciphers found"},
+		{"", "empty script output"},
+		{"  \n ", "empty script output"},
+	}
+	for _, tt := range tests {
+		port := sslPort(tt.output)
+		if port.TLS.Error != tt.want {
+			t.Errorf("output %q: TLS.Error = %q, want %q", tt.output, port.TLS.Error, tt.want)
+		}
+		if port.Grade != "" {
+			t.Errorf("output %q: graded %q, want ungraded", tt.output, port.Grade)
+		}
+	}
+
+	host := ipv4Host("10.0.0.1", nmap.Port{ID: 443, Protocol: "tcp", Scripts: []nmap.Script{
+		{ID: sslEnumCiphers, Output: "No supported ciphers found"},
+	}})
+	_, errs, ok := ParseHostChecked(host)
+	want := []HostError{{IP: "10.0.0.1", Message: "port 443/tcp: ssl-enum-ciphers: No supported ciphers found"}}
+	if !ok || !reflect.DeepEqual(errs, want) {
+		t.Errorf("ParseHostChecked = %+v, %v, want %+v, true", errs, ok, want)
+	}
+}
+
+func TestParseRunGarbageHost(t *testing.T) {
+	good := nmap.Port{ID: 443, Protocol: "tcp", State: nmap.State{State: "open"}, Scripts: []nmap.Script{
+		{ID: sslEnumCiphers, Output: readFixture(t, "ssl_enum_ciphers.txt")},
+	}}
+	garbage := nmap.Port{ID: 443, Protocol: "tcp", State: nmap.State{State: "open"}, Scripts: []nmap.Script{
+		{ID: sslEnumCiphers, Output: "\x00\xff - - ::: (((\n\t\tciphers:\n - A"},
+	}}
+	run := &nmap.Run{Hosts: []nmap.Host{
+		ipv4Host("10.0.0.1", good),
+		ipv4Host("10.0.0.2", garbage),
+		ipv4Host("10.0.0.3", good),
+	}}
+
+	hosts := ParseRun(run)
+	if len(hosts.Hosts) != 3 {
+		t.Fatalf("got %d hosts, want all 3", len(hosts.Hosts))
+	}
+	for _, i := range []int{0, 2} {
+		if grade := hosts.Hosts[i].Ports[0].Grade; grade != "F" {
+			t.Errorf("host %s: grade = %q, want the fixture's F", hosts.Hosts[i].IP, grade)
+		}
+	}
+	if len(hosts.Errors) != 1 || hosts.Errors[0].IP != "10.0.0.2" {
+		t.Errorf("Errors = %+v, want one error for 10.0.0.2", hosts.Errors)
+	}
+}
+
+func TestBestOSMatch(t *testing.T) {
+	matches := []nmap.OSMatch{
+		{Name: "Linux 4.15 - 5.8", Accuracy: 92},
+		{Name: "Linux 5.0 - 5.14", Accuracy: 96},
+		{Name: "Android 10", Accuracy: 96},
+	}
+	want := &OSMatch{Name: "Linux 5.0 - 5.14", Accuracy: 96}
+	if got := bestOSMatch(matches); !reflect.DeepEqual(got, want) {
+		t.Errorf("bestOSMatch = %+v, want %+v, the first of the most accurate", got, want)
+	}
+	if got := bestOSMatch(nil); got != nil {
+		t.Errorf("bestOSMatch(nil) = %+v, want nil", got)
+	}
+
+	host := ipv4Host("10.0.0.1")
+	host.OS.Matches = matches
+	if got := ParseHost(host).OS; !reflect.DeepEqual(got, want) {
+		t.Errorf("HostInfo.OS = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseHostScripts(t *testing.T) {
+	host := ipv4Host("10.0.0.1")
+	host.HostScripts = []nmap.Script{{ID: "smb-os-discovery", Output: "\n  OS: Windows Server 2019\n"}}
+
+	want := map[string]string{"smb-os-discovery": "\n  OS: Windows Server 2019\n"}
+	if got := ParseHost(host).HostScripts; !reflect.DeepEqual(got, want) {
+		t.Errorf("HostScripts = %q, want %q", got, want)
+	}
+	if got := ParseHost(ipv4Host("10.0.0.1")).HostScripts; got != nil {
+		t.Errorf("HostScripts without host scripts = %q, want nil", got)
+	}
+}
+
+func TestParseScriptOutputTLS13Only(t *testing.T) {
+	versions, strength := ParseScriptOutput(readFixture(t, "tls13_only.txt"))
+	if strength != "A" {
+		t.Errorf("least strength = %q, want A", strength)
+	}
+	if _, ok := versions["TLSv1.3"]; !ok || len(versions) != 1 {
+		t.Fatalf("versions = %v, want only TLSv1.3", versions)
+	}
+
+	var tls TLSVersions
+	mergeTLS(&tls, versions, strength)
+	want := []string{
+		"TLS_AKE_WITH_AES_128_GCM_SHA256",
+		"TLS_AKE_WITH_AES_256_GCM_SHA384",
+		"TLS_AKE_WITH_CHACHA20_POLY1305_SHA256",
+	}
+	if got := cipherNames(tls.TLS13); !reflect.DeepEqual(got, want) {
+		t.Errorf("TLSv1.3 ciphers = %q, want %q", got, want)
+	}
+	if !tls.TLS13.ForwardSecrecy || tls.TLS13.WeakCiphers != nil {
+		t.Errorf("TLSv1.3 forward secrecy, weak ciphers = %v, %q, want true and none", tls.TLS13.ForwardSecrecy, tls.TLS13.WeakCiphers)
+	}
+	for _, data := range []CipherData{tls.TLS10, tls.TLS11, tls.TLS12} {
+		if !reflect.DeepEqual(data, CipherData{}) {
+			t.Errorf("older version has data %+v, want none", data)
+		}
+	}
+}
+
+func TestParseScriptOutputWarningsThenLeastStrength(t *testing.T) {
+	output := `
+  TLSv1.2: 
+    ciphers: 
+      TLS_RSA_WITH_3DES_EDE_CBC_SHA (rsa 2048) - C
+    cipher preference: server
+    warnings: 
+      64-bit block cipher 3DES vulnerable to SWEET32 attack
+  least strength: C`
+	versions, strength := ParseScriptOutput(output)
+	want := []string{"64-bit block cipher 3DES vulnerable to SWEET32 attack"}
+	if got := versions["TLSv1.2"].Warnings; !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+	if strength != "C" {
+		t.Errorf("least strength = %q, want C", strength)
+	}
+}
+
+func TestParsePortSTARTTLS(t *testing.T) {
+	enum := []nmap.Script{{ID: "ssl-enum-ciphers", Output: readFixture(t, "ssl_enum_ciphers.txt")}}
+	for _, tt := range []struct {
+		name string
+		port nmap.Port
+		want bool
+	}{
+		{"submission", nmap.Port{ID: 587, Service: nmap.Service{Name: "submission"}, Scripts: enum}, true},
+		{"smtp on a non-standard port", nmap.Port{ID: 2525, Service: nmap.Service{Name: "smtp"}, Scripts: enum}, true},
+		{"smtps tunnel", nmap.Port{ID: 465, Service: nmap.Service{Name: "smtp", Tunnel: "ssl"}, Scripts: enum}, false},
+		{"https", nmap.Port{ID: 443, Service: nmap.Service{Name: "https"}, Scripts: enum}, false},
+		{"no TLS data", nmap.Port{ID: 587, Service: nmap.Service{Name: "submission"}}, false},
+	} {
+		port := parsePort(tt.port)
+		if port.STARTTLS != tt.want {
+			t.Errorf("%s: STARTTLS = %v, want %v", tt.name, port.STARTTLS, tt.want)
+		}
+		if len(tt.port.Scripts) > 0 && len(port.TLS.Offered()) == 0 {
+			t.Errorf("%s: no TLS data attached to port %d", tt.name, tt.port.ID)
+		}
+	}
+}
+
+func TestParseScriptOutputCipherCount(t *testing.T) {
+	for _, fixture := range []string{"ssl_enum_ciphers.txt", "tls13_only.txt"} {
+		versions, _ := ParseScriptOutput(readFixture(t, fixture))
+		if len(versions) == 0 {
+			t.Fatalf("%s: no versions parsed", fixture)
+		}
+		for name, data := range versions {
+			if data.CipherCount != len(data.Ciphers) || data.CipherCount == 0 {
+				t.Errorf("%s %s: CipherCount = %d, want len(Ciphers) = %d", fixture, name, data.CipherCount, len(data.Ciphers))
+			}
+		}
+	}
+
+	// A version without a ciphers section counts zero.
+	versions, _ := ParseScriptOutput("TLSv1.2:\n  compressors:\n    NULL\n")
+	if n := versions["TLSv1.2"].CipherCount; n != 0 {
+		t.Errorf("version without ciphers: CipherCount = %d, want 0", n)
+	}
+}
+
+func TestParseRunMergesDuplicateIPs(t *testing.T) {
+	// The same host listed twice, e.g. as example.com and www.example.com
+	// resolving to one address.
+	first := ipv4Host("93.184.216.34",
+		nmap.Port{ID: 443, Protocol: "tcp", State: nmap.State{State: "open"}},
+	)
+	first.Hostnames = []nmap.Hostname{{Name: "example.com"}}
+	second := ipv4Host("93.184.216.34",
+		nmap.Port{ID: 443, Protocol: "tcp", State: nmap.State{State: "filtered"}},
+		nmap.Port{ID: 8443, Protocol: "tcp", State: nmap.State{State: "open"}},
+	)
+	second.Hostnames = []nmap.Hostname{{Name: "www.example.com"}, {Name: "example.com"}}
+
+	hosts := ParseRun(&nmap.Run{Hosts: []nmap.Host{first, ipv4Host("10.0.0.2"), second}})
+	if len(hosts.Hosts) != 2 {
+		t.Fatalf("got %d hosts, want the duplicate merged into 2", len(hosts.Hosts))
+	}
+	host := hosts.Hosts[0]
+	if host.IP != "93.184.216.34" || hosts.Hosts[1].IP != "10.0.0.2" {
+		t.Errorf("hosts = %s, %s, want the merged host first", host.IP, hosts.Hosts[1].IP)
+	}
+	if want := []string{"example.com", "www.example.com"}; !reflect.DeepEqual(host.Hostnames, want) {
+		t.Errorf("hostnames = %q, want %q", host.Hostnames, want)
+	}
+	var ports []string
+	for _, port := range host.Ports {
+		ports = append(ports, strconv.Itoa(int(port.ID))+" "+port.State)
+	}
+	if want := []string{"443 open", "8443 open"}; !reflect.DeepEqual(ports, want) {
+		t.Errorf("ports = %q, want %q", ports, want)
+	}
+	if want := []AddressInfo{{Addr: "93.184.216.34", Type: "ipv4"}}; !reflect.DeepEqual(host.Addresses, want) {
+		t.Errorf("addresses = %+v, want %+v", host.Addresses, want)
+	}
+	if hosts.Summary.TotalHosts != 2 || hosts.Summary.TotalOpenPorts != 2 {
+		t.Errorf("summary = %+v, want 2 hosts and 2 open ports", hosts.Summary)
+	}
+}
+
+func TestParseRunMergesConflictingPorts(t *testing.T) {
+	// One batch saw 443 filtered, another completed ssl-enum-ciphers on
+	// it: the TLS results win whichever entry comes first.
+	filtered := ipv4Host("93.184.216.34", nmap.Port{ID: 443, Protocol: "tcp", State: nmap.State{State: "filtered"}})
+	scanned := ipv4Host("93.184.216.34", nmap.Port{ID: 443, Protocol: "tcp", State: nmap.State{State: "open"}, Scripts: []nmap.Script{
+		{ID: sslEnumCiphers, Output: readFixture(t, "ssl_enum_ciphers.txt")},
+	}})
+	hosts := ParseRun(&nmap.Run{Hosts: []nmap.Host{filtered, scanned}})
+	if len(hosts.Hosts) != 1 || len(hosts.Hosts[0].Ports) != 1 {
+		t.Fatalf("hosts = %+v, want one host with one port", hosts.Hosts)
+	}
+	if port := hosts.Hosts[0].Ports[0]; port.State != "open" || len(port.TLS.Offered()) == 0 {
+		t.Errorf("port 443 = %s with TLS %v, want the open entry with TLS data", port.State, port.TLS.Offered())
+	}
+	if len(hosts.Errors) != 0 {
+		t.Errorf("errors = %+v, want none", hosts.Errors)
+	}
+
+	// Two virtual hosts on one IP, each scanned with its own SNI, report
+	// different ciphers: the first is kept and the other is reported.
+	vhost := ipv4Host("93.184.216.34", nmap.Port{ID: 443, Protocol: "tcp", State: nmap.State{State: "open"}, Scripts: []nmap.Script{
+		{ID: sslEnumCiphers, Output: readFixture(t, "tls13_only.txt")},
+	}})
+	hosts = ParseRun(&nmap.Run{Hosts: []nmap.Host{scanned, vhost}})
+	if port := hosts.Hosts[0].Ports[0]; len(port.TLS.TLS12.Ciphers) == 0 {
+		t.Errorf("port 443 offers %v, want the first entry's TLSv1.2 ciphers", port.TLS.Offered())
+	}
+	want := []HostError{{IP: "93.184.216.34", Message: "port 443/tcp: dropped differing ssl-enum-ciphers results of a duplicate host entry"}}
+	if !reflect.DeepEqual(hosts.Errors, want) {
+		t.Errorf("errors = %+v, want %+v", hosts.Errors, want)
+	}
+}
@@ -0,0 +1,51 @@
+package sslparse
+
+// Summary holds aggregate counts over the hosts of a report.
+type Summary struct {
+	TotalHosts             int `json:"total_hosts" yaml:"total_hosts"`
+	HostsUp                int `json:"hosts_up" yaml:"hosts_up"`
+	TotalOpenPorts         int `json:"total_open_ports" yaml:"total_open_ports"`
+	PortsWithWeakCiphers   int `json:"ports_with_weak_ciphers" yaml:"ports_with_weak_ciphers"`
+	PortsWithDeprecatedTLS int `json:"ports_with_deprecated_tls" yaml:"ports_with_deprecated_tls"`
+}
+
+// Summarize computes the summary counts for hosts.
+func Summarize(hosts []HostInfo) Summary {
+	var s Summary
+	for _, host := range hosts {
+		s.TotalHosts++
+		if host.Status == "up" {
+			s.HostsUp++
+		}
+		for _, port := range host.Ports {
+			if port.State == "open" {
+				s.TotalOpenPorts++
+			}
+			weak, deprecated := false, false
+			for _, v := range port.TLS.Versions() {
+				if len(v.Data.WeakCiphers) > 0 {
+					weak = true
+				}
+				if v.Deprecated() && len(v.Data.Ciphers) > 0 {
+					deprecated = true
+				}
+			}
+			if weak {
+				s.PortsWithWeakCiphers++
+			}
+			if deprecated {
+				s.PortsWithDeprecatedTLS++
+			}
+		}
+	}
+	return s
+}
+
+// Add adds the counts of o to s, e.g. to summarise hosts one at a time.
+func (s *Summary) Add(o Summary) {
+	s.TotalHosts += o.TotalHosts
+	s.HostsUp += o.HostsUp
+	s.TotalOpenPorts += o.TotalOpenPorts
+	s.PortsWithWeakCiphers += o.PortsWithWeakCiphers
+	s.PortsWithDeprecatedTLS += o.PortsWithDeprecatedTLS
+}
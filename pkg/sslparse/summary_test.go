@@ -0,0 +1,49 @@
+package sslparse
+
+import (
+	"testing"
+
+	nmap "github.com/Ullaakut/nmap/v3"
+)
+
+func TestSummarize(t *testing.T) {
+	down := ipv4Host("10.0.0.3")
+	down.Status.State = "down"
+	run := &nmap.Run{Hosts: []nmap.Host{
+		ipv4Host("10.0.0.1",
+			nmap.Port{ID: 443, Protocol: "tcp", State: nmap.State{State: "open"}, Scripts: []nmap.Script{
+				{ID: sslEnumCiphers, Output: readFixture(t, "ssl_enum_ciphers.txt")},
+			}},
+			nmap.Port{ID: 80, Protocol: "tcp", State: nmap.State{State: "open"}},
+			nmap.Port{ID: 8443, Protocol: "tcp", State: nmap.State{State: "filtered"}},
+		),
+		ipv4Host("10.0.0.2",
+			nmap.Port{ID: 443, Protocol: "tcp", State: nmap.State{State: "open"}, Scripts: []nmap.Script{
+				{ID: sslEnumCiphers, Output: tls10RC4},
+			}},
+			nmap.Port{ID: 8443, Protocol: "tcp", State: nmap.State{State: "open"}, Scripts: []nmap.Script{
+				{ID: sslEnumCiphers, Output: tls13Only},
+			}},
+		),
+		down,
+	}}
+
+	want := Summary{
+		TotalHosts:             3,
+		HostsUp:                2,
+		TotalOpenPorts:         4,
+		PortsWithWeakCiphers:   2,
+		PortsWithDeprecatedTLS: 2,
+	}
+	if got := ParseRun(run).Summary; got != want {
+		t.Errorf("Summary = %+v, want %+v", got, want)
+	}
+
+	var sum Summary
+	for _, host := range ParseRun(run).Hosts {
+		sum.Add(Summarize([]HostInfo{host}))
+	}
+	if sum != want {
+		t.Errorf("per-host summaries added up to %+v, want %+v", sum, want)
+	}
+}
@@ -0,0 +1,257 @@
+// Package sslparse turns nmap scan results, and in particular the output of
+// the ssl-enum-ciphers NSE script, into structured data.
+package sslparse
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// CipherData is the parsed ssl-enum-ciphers data for one TLS version.
+//
+// Reports also carry Ciphers under the "ciphers" key and the bare cipher
+// names under "cipher_names", the keys used before "offered_ciphers", for
+// existing consumers. Both are derived from Ciphers when encoding, see
+// MarshalJSON.
+type CipherData struct {
+	// Ciphers lists the cipher suites the server accepted while
+	// ssl-enum-ciphers enumerated them. They are offered, not negotiated:
+	// a client connecting to the server ends up with only one of them.
+	Ciphers []Cipher `json:"offered_ciphers" yaml:"offered_ciphers"`
+	// CipherCount is len(Ciphers), for quick comparisons between versions.
+	CipherCount int      `json:"cipher_count" yaml:"cipher_count"`
+	Compressors []string `json:"compressors" yaml:"compressors"`
+	Preference  string   `json:"cipher_preference" yaml:"cipher_preference"`
+	Warnings    []string `json:"warnings" yaml:"warnings"`
+	WeakCiphers []string `json:"weak_ciphers" yaml:"weak_ciphers"`
+	// DisallowedCiphers lists the ciphers missing from the allowlist given
+	// with -allowed-ciphers-file. It is empty when no allowlist is used.
+	DisallowedCiphers []string `json:"disallowed_ciphers,omitempty" yaml:"disallowed_ciphers,omitempty"`
+	// ForwardSecrecy is true when every offered cipher uses an ephemeral
+	// key exchange; NonFSCiphers lists the ones that do not.
+	ForwardSecrecy bool     `json:"forward_secrecy" yaml:"forward_secrecy"`
+	NonFSCiphers   []string `json:"non_fs_ciphers" yaml:"non_fs_ciphers"`
+	// LeastStrength is the weakest grade for this version, as reported by
+	// nmap or, failing that, derived from the individual cipher grades.
+	LeastStrength string `json:"least_strength" yaml:"least_strength"`
+}
+
+// cipherData has the fields of CipherData without its methods, so the
+// methods can encode it without calling themselves.
+type cipherData CipherData
+
+// legacyCipherData is CipherData with the keys kept for compatibility.
+type legacyCipherData struct {
+	cipherData  `yaml:",inline"`
+	Ciphers     []Cipher `json:"ciphers" yaml:"ciphers"`
+	CipherNames []string `json:"cipher_names" yaml:"cipher_names"`
+}
+
+func (d CipherData) legacy() legacyCipherData {
+	var names []string
+	for _, cipher := range d.Ciphers {
+		names = append(names, cipher.Name)
+	}
+	return legacyCipherData{cipherData(d), d.Ciphers, names}
+}
+
+// MarshalJSON encodes d with the legacy "ciphers" and "cipher_names" keys.
+func (d CipherData) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.legacy())
+}
+
+// MarshalYAML is the YAML counterpart of MarshalJSON.
+func (d CipherData) MarshalYAML() (interface{}, error) {
+	return d.legacy(), nil
+}
+
+// UnmarshalJSON decodes d, taking Ciphers from the legacy "ciphers" key
+// for reports written before "offered_ciphers" existed. Unknown keys are
+// an error: a custom unmarshaler does not inherit the caller's
+// DisallowUnknownFields, and a misspelled key would otherwise silently
+// drop the ciphers, e.g. from a -baseline report.
+func (d *CipherData) UnmarshalJSON(data []byte) error {
+	var v legacyCipherData
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	*d = CipherData(v.cipherData)
+	if d.Ciphers == nil {
+		d.Ciphers = v.Ciphers
+	}
+	return nil
+}
+
+// Cipher is a single cipher suite offered for a TLS version.
+type Cipher struct {
+	Name string `json:"name" yaml:"name"`
+	// KeyInfo is the parenthesised key exchange detail nmap prints after
+	// the name, e.g. "secp256r1" or "rsa 2048".
+	KeyInfo  string `json:"key_info,omitempty" yaml:"key_info,omitempty"`
+	Strength string `json:"strength" yaml:"strength"`
+}
+
+// TLSVersions groups the cipher data of every TLS version offered on a port.
+type TLSVersions struct {
+	// SSL3 is only filled in for servers that still accept SSLv3.
+	SSL3  CipherData `json:"SSLv3" yaml:"SSLv3"`
+	TLS10 CipherData `json:"TLSv1.0" yaml:"TLSv1.0"`
+	TLS11 CipherData `json:"TLSv1.1" yaml:"TLSv1.1"`
+	TLS12 CipherData `json:"TLSv1.2" yaml:"TLSv1.2"`
+	TLS13 CipherData `json:"TLSv1.3" yaml:"TLSv1.3"`
+	// Strength is the weakest grade across all versions.
+	Strength string `json:"least_strength" yaml:"least_strength"`
+	// Error is set instead of any version data when ssl-enum-ciphers ran
+	// but reported no TLS versions, e.g. "No supported ciphers found".
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+	// Raw is the ssl-enum-ciphers output the fields above were parsed
+	// from.
+	Raw string `json:"raw,omitempty" yaml:"raw,omitempty"`
+}
+
+// HostInfo is the parsed result for a single scanned host.
+type HostInfo struct {
+	IP        string   `json:"ip" yaml:"ip"`
+	Hostnames []string `json:"hostnames" yaml:"hostnames"`
+	// Status is the host state reported by nmap, e.g. "up" or "down".
+	Status string `json:"status" yaml:"status"`
+	// Addresses lists every address reported for the host, while IP
+	// only holds the primary one.
+	Addresses []AddressInfo `json:"addresses,omitempty" yaml:"addresses,omitempty"`
+	// MAC and Vendor are only known for hosts on the local network.
+	MAC    string `json:"mac,omitempty" yaml:"mac,omitempty"`
+	Vendor string `json:"vendor,omitempty" yaml:"vendor,omitempty"`
+	// LatencySeconds is the smoothed round-trip time nmap measured.
+	LatencySeconds float64 `json:"latency_seconds,omitempty" yaml:"latency_seconds,omitempty"`
+	// OS is the most accurate OS detection (-O) match, if any.
+	OS *OSMatch `json:"os,omitempty" yaml:"os,omitempty"`
+	// HostScripts holds the raw output of host-level scripts, such as
+	// smb-os-discovery, keyed by script id.
+	HostScripts map[string]string `json:"host_scripts,omitempty" yaml:"host_scripts,omitempty"`
+	Ports       []Port            `json:"ports" yaml:"ports"`
+}
+
+// OSMatch is an operating system guess with nmap's confidence in percent.
+type OSMatch struct {
+	Name     string `json:"name" yaml:"name"`
+	Accuracy int    `json:"accuracy" yaml:"accuracy"`
+}
+
+// AddressInfo is a single address of a host.
+type AddressInfo struct {
+	Addr string `json:"addr" yaml:"addr"`
+	// Type is the nmap address type: "ipv4", "ipv6" or "mac".
+	Type string `json:"type" yaml:"type"`
+}
+
+// Port is the parsed result for a single port of a host.
+type Port struct {
+	ID       uint16 `json:"id" yaml:"id"`
+	Protocol string `json:"protocol" yaml:"protocol"`
+	Service  string `json:"service" yaml:"service"`
+	State    string `json:"state" yaml:"state"`
+	// StateReason is why nmap considers the port to be in State, e.g.
+	// "reset" or "no-response" for a filtered port.
+	StateReason string      `json:"state_reason,omitempty" yaml:"state_reason,omitempty"`
+	TLS         TLSVersions `json:"ssl-enum-ciphers" yaml:"ssl-enum-ciphers"`
+	// Grade is an SSL Labs-style letter (A-F) summarising TLS. It is
+	// empty for ports without ssl-enum-ciphers data.
+	Grade string `json:"grade,omitempty" yaml:"grade,omitempty"`
+	// GradeReasons explains why Grade is below an A, one entry per
+	// deduction. It is empty for an A or an ungraded port.
+	GradeReasons []string `json:"grade_reasons,omitempty" yaml:"grade_reasons,omitempty"`
+	// Product, Version and ExtraInfo are only filled in when service
+	// version detection (-sV) is enabled.
+	Product   string `json:"product,omitempty" yaml:"product,omitempty"`
+	Version   string `json:"version,omitempty" yaml:"version,omitempty"`
+	ExtraInfo string `json:"extra_info,omitempty" yaml:"extra_info,omitempty"`
+	// Tunnel is "ssl" when nmap found the service wrapped in SSL/TLS, as
+	// opposed to a plain service that may still offer STARTTLS.
+	Tunnel string `json:"tunnel,omitempty" yaml:"tunnel,omitempty"`
+	// STARTTLS is set when TLS was negotiated by upgrading a plain
+	// protocol, e.g. SMTP on port 587, rather than by a TLS tunnel.
+	STARTTLS bool `json:"starttls,omitempty" yaml:"starttls,omitempty"`
+	// BelowMinTLS lists the offered versions older than the one given with
+	// -min-tls. It is empty when no minimum is set.
+	BelowMinTLS []string `json:"below_min_tls,omitempty" yaml:"below_min_tls,omitempty"`
+	// Certificate is set when the ssl-cert script ran on the port.
+	Certificate *Certificate `json:"certificate,omitempty" yaml:"certificate,omitempty"`
+	// Scripts holds the raw output of every script other than
+	// ssl-enum-ciphers, keyed by script id, since only ssl-enum-ciphers
+	// output is understood by the parser.
+	Scripts map[string]string `json:"scripts,omitempty" yaml:"scripts,omitempty"`
+	// RawScriptOutput holds the untouched output of every script,
+	// including ssl-enum-ciphers, keyed by script id. It is meant for
+	// debugging the parser.
+	RawScriptOutput map[string]string `json:"raw_script_output,omitempty" yaml:"raw_script_output,omitempty"`
+}
+
+// Hosts is the top-level report produced by ParseRun.
+type Hosts struct {
+	Meta *ScanMeta `json:"scan,omitempty" yaml:"scan,omitempty"`
+	// Note explains how to read the cipher lists; it is always OfferedNote
+	// in reports produced by ParseRun.
+	Note    string     `json:"note,omitempty" yaml:"note,omitempty"`
+	Summary Summary    `json:"summary" yaml:"summary"`
+	Hosts   []HostInfo `json:"hosts" yaml:"hosts"`
+	// Errors lists the problems met while parsing individual hosts. A
+	// host that could not be parsed at all is left out of Hosts.
+	Errors []HostError `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// HostError is a parse problem for a single host.
+type HostError struct {
+	IP      string `json:"ip" yaml:"ip"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// ScanMeta records when and how the scan behind a report was run.
+type ScanMeta struct {
+	StartedAt      time.Time `json:"started_at" yaml:"started_at"`
+	FinishedAt     time.Time `json:"finished_at" yaml:"finished_at"`
+	ElapsedSeconds float64   `json:"elapsed_seconds" yaml:"elapsed_seconds"`
+	NmapVersion    string    `json:"nmap_version" yaml:"nmap_version"`
+	Args           string    `json:"args" yaml:"args"`
+}
+
+const sslEnumCiphers = "ssl-enum-ciphers"
+
+// OfferedNote is the Note of every report.
+const OfferedNote = "cipher lists are the suites each server offered to ssl-enum-ciphers, not the suite a client negotiates"
+
+// Version pairs a TLS protocol name with its cipher data.
+type Version struct {
+	Name string
+	Data CipherData
+}
+
+// Versions returns the per-version cipher data in protocol order.
+func (t TLSVersions) Versions() []Version {
+	return []Version{
+		{"SSLv3", t.SSL3},
+		{"TLSv1.0", t.TLS10},
+		{"TLSv1.1", t.TLS11},
+		{"TLSv1.2", t.TLS12},
+		{"TLSv1.3", t.TLS13},
+	}
+}
+
+// Deprecated reports whether the protocol version is deprecated, SSLv3 by
+// RFC 7568 and TLS 1.0 and 1.1 by RFC 8996.
+func (v Version) Deprecated() bool {
+	return v.Name == "SSLv3" || v.Name == "TLSv1.0" || v.Name == "TLSv1.1"
+}
+
+// Offered returns the names of the versions that offer at least one cipher.
+func (t TLSVersions) Offered() []string {
+	var offered []string
+	for _, v := range t.Versions() {
+		if len(v.Data.Ciphers) > 0 {
+			offered = append(offered, v.Name)
+		}
+	}
+	return offered
+}
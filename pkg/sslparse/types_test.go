@@ -0,0 +1,64 @@
+package sslparse
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestCipherDataLegacyKeys(t *testing.T) {
+	data := CipherData{Ciphers: []Cipher{{Name: "TLS_AKE_WITH_AES_128_GCM_SHA256", Strength: "A"}}, CipherCount: 1}
+	wantNames := []string{"TLS_AKE_WITH_AES_128_GCM_SHA256"}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys struct {
+		Offered     []Cipher `json:"offered_ciphers"`
+		Ciphers     []Cipher `json:"ciphers"`
+		CipherNames []string `json:"cipher_names"`
+	}
+	if err := json.Unmarshal(encoded, &keys); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(keys.Offered, data.Ciphers) || !reflect.DeepEqual(keys.Ciphers, data.Ciphers) || !reflect.DeepEqual(keys.CipherNames, wantNames) {
+		t.Errorf("JSON = %s, want the ciphers under offered_ciphers and ciphers and their names under cipher_names", encoded)
+	}
+
+	var decoded CipherData
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, data) {
+		t.Errorf("decoded %+v, want %+v", decoded, data)
+	}
+
+	// Reports written before offered_ciphers only have ciphers.
+	decoded = CipherData{}
+	if err := json.Unmarshal([]byte(`{"ciphers":[{"name":"TLS_AKE_WITH_AES_128_GCM_SHA256","strength":"A"}],"cipher_count":1}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, data) {
+		t.Errorf("decoded legacy report %+v, want %+v", decoded, data)
+	}
+	if err := json.Unmarshal([]byte(`{"ciphrs":[]}`), &decoded); err == nil {
+		t.Error("unknown key: got no error")
+	}
+
+	out, err := yaml.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var yamlKeys map[string]interface{}
+	if err := yaml.Unmarshal(out, &yamlKeys); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"offered_ciphers", "ciphers", "cipher_names", "cipher_count"} {
+		if _, ok := yamlKeys[key]; !ok {
+			t.Errorf("YAML has no %s key:\n%s", key, out)
+		}
+	}
+}
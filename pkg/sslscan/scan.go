@@ -0,0 +1,164 @@
+// Package sslscan runs nmap with the ssl-enum-ciphers script and returns
+// the parsed results, for embedding TLS scans in other Go programs.
+package sslscan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	nmap "github.com/Ullaakut/nmap/v3"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// Defaults used for ScanOptions fields that are left empty.
+var (
+	DefaultPorts   = []string{"443", "80"}
+	DefaultScripts = []string{"ssl-enum-ciphers"}
+)
+
+// ScanOptions describes a scan.
+type ScanOptions struct {
+	// Targets are the hostnames, IPs or CIDR ranges to scan.
+	Targets []string
+	// Ports to scan; DefaultPorts when empty. Ignored when TopPorts is set.
+	Ports []string
+	// TopPorts scans the N most common ports instead of Ports.
+	TopPorts int
+	// Scripts are the NSE scripts to run; DefaultScripts when empty.
+	Scripts []string
+	// Timeout bounds the whole scan. Zero means no timeout.
+	Timeout time.Duration
+	// Options are additional nmap options, applied after the ones above,
+	// e.g. nmap.WithServiceInfo().
+	Options []nmap.Option
+}
+
+// NmapOptions translates opts into nmap scanner options. Timeout is not
+// included, as it is applied through the context by Scan.
+func (opts ScanOptions) NmapOptions() []nmap.Option {
+	scripts := opts.Scripts
+	if len(scripts) == 0 {
+		scripts = DefaultScripts
+	}
+	nmapOpts := []nmap.Option{
+		nmap.WithTargets(opts.Targets...),
+		nmap.WithScripts(scripts...),
+	}
+	if opts.TopPorts > 0 {
+		nmapOpts = append(nmapOpts, nmap.WithMostCommonPorts(opts.TopPorts))
+	} else {
+		ports := opts.Ports
+		if len(ports) == 0 {
+			ports = DefaultPorts
+		}
+		nmapOpts = append(nmapOpts, nmap.WithPorts(ports...))
+	}
+	return append(nmapOpts, opts.Options...)
+}
+
+// NewScanner creates an nmap scanner for opts, for callers that need the
+// scanner itself, e.g. to follow its progress. It ignores opts.Timeout.
+func NewScanner(ctx context.Context, opts ScanOptions) (*nmap.Scanner, error) {
+	return nmap.NewScanner(ctx, opts.NmapOptions()...)
+}
+
+// Scan runs nmap as described by opts and returns the parsed results
+// together with the warnings nmap printed. If the scan is cut short because
+// ctx ended or the timeout expired, the hosts nmap finished before that are
+// returned along with the error.
+func Scan(ctx context.Context, opts ScanOptions) (sslparse.Hosts, *[]string, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	scanner, err := NewScanner(ctx, opts)
+	if err != nil {
+		return sslparse.Hosts{}, nil, err
+	}
+	result, warnings, err := Run(ctx, scanner)
+	if result == nil {
+		return sslparse.Hosts{}, warnings, err
+	}
+	return sslparse.ParseRun(result), warnings, err
+}
+
+// Run runs scanner, which must have been created with ctx, and returns the
+// raw nmap result. nmap writes its XML to a temporary file, see
+// nmap.Scanner.ToFile, rather than to stdout: when nmap is killed because
+// ctx ended the library parses nothing, and Run then returns the hosts
+// nmap finished from that file along with the error.
+func Run(ctx context.Context, scanner *nmap.Scanner) (*nmap.Run, *[]string, error) {
+	return RunWatch(ctx, scanner, Watch{})
+}
+
+// RunWatch is Run that also reports on the scan while nmap runs, as set
+// out by watch.
+func RunWatch(ctx context.Context, scanner *nmap.Scanner, watch Watch) (*nmap.Run, *[]string, error) {
+	if watch.Progress != nil {
+		defer close(watch.Progress)
+	}
+	f, err := os.CreateTemp("", "sslscan-*.xml")
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating the XML output file: %w", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	scanner.ToFile(f.Name())
+
+	var w *watcher
+	if watch.Progress != nil || watch.Host != nil {
+		if watch.Progress != nil {
+			scanner.AddOptions(nmap.WithStatsEvery(progressInterval.String()))
+		}
+		w = &watcher{path: f.Name(), watch: watch}
+		stop, done := make(chan struct{}), make(chan struct{})
+		go func() {
+			w.run(stop)
+			close(done)
+		}()
+		defer func() {
+			close(stop)
+			<-done
+			// Hand over the hosts nmap wrote since the last poll.
+			w.poll()
+		}()
+	}
+
+	result, warnings, err := scanner.Run()
+	if err != nil && ctx.Err() != nil {
+		if output, rerr := os.ReadFile(f.Name()); rerr == nil {
+			if partial, perr := partialRun(output); perr == nil {
+				result = partial
+			}
+		}
+	}
+	return result, warnings, err
+}
+
+// partialRun parses the XML output of an nmap run that was stopped before
+// it finished. nmap writes every host element in one piece, so the output
+// is cut after the last complete host and the root element is closed.
+// Attribute values and text have "<" escaped, so "</host>" cannot appear
+// inside them.
+func partialRun(output []byte) (*nmap.Run, error) {
+	const hostEnd = "</host>"
+	end := bytes.LastIndex(output, []byte(hostEnd))
+	if end < 0 {
+		return nil, errors.New("nmap did not finish any host")
+	}
+	end += len(hostEnd)
+	doc := append(output[:end:end], "\n</nmaprun>\n"...)
+
+	result := &nmap.Run{}
+	if err := nmap.Parse(doc, result); err != nil {
+		return nil, fmt.Errorf("parsing partial output: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,104 @@
+package sslscan
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	nmap "github.com/Ullaakut/nmap/v3"
+)
+
+// The fake nmap and its scan are shared with the main package's tests.
+const (
+	fakeNmap = "../../testdata/fake-nmap"
+	scanXML  = "../../testdata/scan.xml"
+)
+
+func TestScan(t *testing.T) {
+	// fake-nmap prints scanXML instead of scanning, so no network access
+	// is needed.
+	t.Setenv("FAKE_NMAP_XML", scanXML)
+	hosts, warnings, err := Scan(context.Background(), ScanOptions{
+		Targets: []string{"example.com"},
+		Options: []nmap.Option{nmap.WithBinaryPath(fakeNmap)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warnings == nil || len(*warnings) != 1 || !strings.Contains((*warnings)[0], "fake-nmap") {
+		t.Errorf("warnings = %v, want the fake-nmap warning", warnings)
+	}
+	if len(hosts.Hosts) != 2 {
+		t.Fatalf("got %d hosts, want 2", len(hosts.Hosts))
+	}
+	host := hosts.Hosts[0]
+	if host.IP != "93.184.216.34" || len(host.Ports) != 2 {
+		t.Fatalf("first host = %s with %d ports, want 93.184.216.34 with 2", host.IP, len(host.Ports))
+	}
+	if tls := host.Ports[1].TLS; tls.TLS12.CipherCount == 0 || tls.Strength == "" {
+		t.Errorf("port 443 has no parsed ssl-enum-ciphers data: %+v", tls)
+	}
+	if hosts.Summary.TotalHosts != 2 {
+		t.Errorf("Summary.TotalHosts = %d, want 2", hosts.Summary.TotalHosts)
+	}
+}
+
+func TestScanTimeoutKeepsFinishedHosts(t *testing.T) {
+	hosts, _, err := Scan(context.Background(), ScanOptions{
+		Targets: []string{"example.com"},
+		Timeout: 200 * time.Millisecond,
+		Options: []nmap.Option{nmap.WithBinaryPath("testdata/slow-nmap")},
+	})
+	if err == nil {
+		t.Fatal("got no error from a scan that timed out")
+	}
+	if len(hosts.Hosts) != 1 || hosts.Hosts[0].IP != "93.184.216.34" {
+		t.Errorf("hosts = %+v, want the one host nmap finished", hosts.Hosts)
+	}
+}
+
+func TestScanOptionsDefaults(t *testing.T) {
+	scanner, err := NewScanner(context.Background(), ScanOptions{
+		Targets: []string{"example.com"},
+		Options: []nmap.Option{nmap.WithBinaryPath(fakeNmap)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := strings.Join(scanner.Args(), " ")
+	for _, want := range []string{"-p 443,80", "--script=ssl-enum-ciphers", "example.com"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args %q do not contain %q", args, want)
+		}
+	}
+}
+
+func TestPartialRun(t *testing.T) {
+	full, err := os.ReadFile(scanXML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// nmap was killed while writing the second host.
+	second := strings.LastIndex(string(full), "<host ")
+	output := full[:second+40]
+
+	result, err := partialRun(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Hosts) != 1 {
+		t.Fatalf("got %d hosts, want the 1 complete host", len(result.Hosts))
+	}
+	if got := result.Hosts[0].Addresses[0].Addr; got != "93.184.216.34" {
+		t.Errorf("host address = %q, want 93.184.216.34", got)
+	}
+
+	if _, err := partialRun(full[:second]); err != nil {
+		t.Errorf("output ending after a host: %v", err)
+	}
+	if _, err := partialRun(full[:strings.Index(string(full), "<host ")]); err == nil {
+		t.Error("output without a complete host: got no error")
+	}
+}
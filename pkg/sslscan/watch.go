@@ -0,0 +1,137 @@
+package sslscan
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	nmap "github.com/Ullaakut/nmap/v3"
+)
+
+// Watch is what RunWatch reports while nmap runs. Both fields are
+// optional. They replace nmap.Scanner.Progress, which reads the progress
+// from stdout and so sees none once the XML goes to a file.
+type Watch struct {
+	// Progress receives nmap's completion percentage. RunWatch closes it
+	// before it returns.
+	Progress chan<- float32
+	// Host is called with every host as soon as nmap has written it, from
+	// a single goroutine, and for the last hosts before RunWatch returns.
+	// Hosts written by a scan that is cut short are passed as well.
+	Host func(nmap.Host)
+}
+
+const (
+	// progressInterval is how often nmap is asked to report its progress.
+	progressInterval = time.Second
+	// pollInterval is how often the output file is checked for new hosts
+	// and progress.
+	pollInterval = 250 * time.Millisecond
+)
+
+var (
+	// taskProgress matches the completion percentage of a taskprogress
+	// element, which nmap writes to its XML output every progressInterval.
+	taskProgress = regexp.MustCompile(`<taskprogress [^>]*percent="([0-9.]+)"`)
+	// hostStart matches the start of a host element, but not of hostnames
+	// or hosthint.
+	hostStart = regexp.MustCompile(`<host[ >]`)
+)
+
+// watcher follows the XML file nmap writes at path.
+type watcher struct {
+	path  string
+	watch Watch
+	// offset is where the next poll continues reading.
+	offset int64
+}
+
+// run polls the file every pollInterval and sends the latest progress on
+// w.watch.Progress, until stop is closed.
+func (w *watcher) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+		percent, ok := w.poll()
+		if !ok || w.watch.Progress == nil {
+			continue
+		}
+		select {
+		case w.watch.Progress <- percent:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// poll reads what nmap appended to the file since the last poll, calls
+// w.watch.Host for every complete host in it, and returns the percentage
+// of the last taskprogress element, if there was one. A host or element
+// that nmap has only partly written is read again by the next poll.
+func (w *watcher) poll() (float32, bool) {
+	f, err := os.Open(w.path)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	if _, err := f.Seek(w.offset, io.SeekStart); err != nil {
+		return 0, false
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return 0, false
+	}
+
+	// Hosts are parsed in full; everything before the end of the last
+	// complete host is consumed.
+	consumed := 0
+	for {
+		loc := hostStart.FindIndex(data[consumed:])
+		if loc == nil {
+			break
+		}
+		start := consumed + loc[0]
+		end := bytes.Index(data[start:], []byte("</host>"))
+		if end < 0 {
+			break
+		}
+		end += start + len("</host>")
+		if w.watch.Host != nil {
+			var host nmap.Host
+			if err := xml.Unmarshal(data[start:end], &host); err == nil {
+				w.watch.Host(host)
+			}
+		}
+		consumed = end
+	}
+
+	// Progress is taken from the complete elements, up to the start of a
+	// host that is still being written.
+	complete := bytes.LastIndexByte(data, '>') + 1
+	if loc := hostStart.FindIndex(data[consumed:]); loc != nil {
+		complete = consumed + loc[0]
+	}
+	if complete < consumed {
+		complete = consumed
+	}
+	w.offset += int64(complete)
+
+	matches := taskProgress.FindAllSubmatch(data[:complete], -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+	percent, err := strconv.ParseFloat(string(matches[len(matches)-1][1]), 32)
+	if err != nil {
+		return 0, false
+	}
+	return float32(percent), true
+}
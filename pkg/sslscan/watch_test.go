@@ -0,0 +1,132 @@
+package sslscan
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	nmap "github.com/Ullaakut/nmap/v3"
+)
+
+func TestWatcherPoll(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.xml")
+	write := func(s string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var hosts []string
+	w := &watcher{path: path, watch: Watch{Host: func(h nmap.Host) {
+		hosts = append(hosts, h.Addresses[0].Addr)
+	}}}
+
+	write(`<nmaprun><taskprogress task="SYN Stealth Scan" time="1" percent="12.50" remaining="9"/>` + "\n" +
+		`<taskprogress task="SYN Stealth Scan" time="2" percent="40.00" remaining="5"/>` + "\n" +
+		`<host><address addr="192.0.2.1" addrtype="ipv4"/><hostnames><hostname name="a.example"/></hostnames></host>` + "\n" +
+		`<host><address addr="192.0.2.2" addrtype="ipv4"/><ports>`)
+	percent, ok := w.poll()
+	if !ok || percent != 40 {
+		t.Fatalf("poll = %v, %v, want 40, true", percent, ok)
+	}
+	if len(hosts) != 1 || hosts[0] != "192.0.2.1" {
+		t.Fatalf("hosts = %q, want the complete one", hosts)
+	}
+
+	// The host cut off above is read again once it is complete.
+	write(`</ports></host>` + "\n" + `<taskprogress task="NSE" perc`)
+	if _, ok := w.poll(); ok {
+		t.Error("poll of a cut off taskprogress: got progress")
+	}
+	if len(hosts) != 2 || hosts[1] != "192.0.2.2" {
+		t.Fatalf("hosts = %q, want both", hosts)
+	}
+	write(`ent="75.00" remaining="1"/>` + "\n")
+	if percent, ok := w.poll(); !ok || percent != 75 {
+		t.Errorf("poll after the element completed = %v, %v, want 75, true", percent, ok)
+	}
+	if len(hosts) != 2 {
+		t.Errorf("hosts = %q, want no host passed twice", hosts)
+	}
+
+	missing := &watcher{path: filepath.Join(t.TempDir(), "missing.xml")}
+	if _, ok := missing.poll(); ok {
+		t.Error("missing file: got progress")
+	}
+}
+
+func TestRunWatchClosesProgress(t *testing.T) {
+	t.Setenv("FAKE_NMAP_XML", scanXML)
+	scanner, err := NewScanner(context.Background(), ScanOptions{
+		Targets: []string{"example.com"},
+		Options: []nmap.Option{nmap.WithBinaryPath(fakeNmap)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress := make(chan float32)
+	go func() {
+		for range progress {
+		}
+	}()
+	var hosts int
+	result, _, err := RunWatch(context.Background(), scanner, Watch{
+		Progress: progress,
+		Host:     func(nmap.Host) { hosts++ },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Hosts) != 2 || hosts != 2 {
+		t.Errorf("got %d hosts and %d from Host, want 2", len(result.Hosts), hosts)
+	}
+	if _, open := <-progress; open {
+		t.Error("progress is still open after RunWatch returned")
+	}
+	if args := strings.Join(scanner.Args(), " "); !strings.Contains(args, "--stats-every 1s") {
+		t.Errorf("args %q do not ask nmap for progress", args)
+	}
+}
+
+func TestRunWatchHostBeforeScanEnds(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scanner, err := NewScanner(ctx, ScanOptions{
+		Targets: []string{"example.com"},
+		Options: []nmap.Option{nmap.WithBinaryPath("testdata/slow-nmap")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// slow-nmap hangs after its first host, so the host has to arrive
+	// while nmap is still running.
+	first, done := make(chan string, 1), make(chan struct{})
+	go func() {
+		RunWatch(ctx, scanner, Watch{Host: func(h nmap.Host) {
+			select {
+			case first <- h.Addresses[0].Addr:
+			default:
+			}
+		}})
+		close(done)
+	}()
+	select {
+	case ip := <-first:
+		if ip != "93.184.216.34" {
+			t.Errorf("first host = %s, want 93.184.216.34", ip)
+		}
+	case <-done:
+		t.Fatal("no host before the scan ended")
+	case <-time.After(10 * time.Second):
+		t.Fatal("no host within 10s")
+	}
+	cancel()
+	<-done
+}
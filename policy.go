@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// cipherAllowlist is a set of approved cipher names, keyed by cipherKey.
+type cipherAllowlist map[string]bool
+
+// readAllowedCiphers loads an allowlist with one cipher per line. Lines may
+// be copied from ssl-enum-ciphers output, so a trailing key exchange or
+// strength suffix such as "(secp256r1) - A" is ignored.
+func readAllowedCiphers(path string) (cipherAllowlist, error) {
+	entries, err := readListFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading allowed ciphers file: %w", err)
+	}
+	allowed := make(cipherAllowlist, len(entries))
+	for _, entry := range entries {
+		allowed[cipherKey(entry)] = true
+	}
+	return allowed, nil
+}
+
+// cipherKey normalises a cipher name for case-insensitive comparison,
+// dropping anything after the name itself.
+func cipherKey(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
+// apply records, for every TLS version of every port, the offered ciphers
+// that are not on the allowlist.
+func (allowed cipherAllowlist) apply(hosts sslparse.Hosts) {
+	for h := range hosts.Hosts {
+		for p := range hosts.Hosts[h].Ports {
+			tls := &hosts.Hosts[h].Ports[p].TLS
+			for _, data := range []*sslparse.CipherData{&tls.SSL3, &tls.TLS10, &tls.TLS11, &tls.TLS12, &tls.TLS13} {
+				data.DisallowedCiphers = nil
+				for _, cipher := range data.Ciphers {
+					if !allowed[cipherKey(cipher.Name)] {
+						data.DisallowedCiphers = append(data.DisallowedCiphers, cipher.Name)
+					}
+				}
+			}
+		}
+	}
+}
+
+// disallowedCiphers describes every offered cipher that is not on the
+// allowlist, as recorded by cipherAllowlist.apply.
+func disallowedCiphers(hosts sslparse.Hosts) []string {
+	var offenders []string
+	for _, host := range hosts.Hosts {
+		for _, port := range host.Ports {
+			for _, v := range port.TLS.Versions() {
+				for _, cipher := range v.Data.DisallowedCiphers {
+					offenders = append(offenders, fmt.Sprintf("%s:%d offers %s with %s", host.IP, port.ID, cipher, v.Name))
+				}
+			}
+		}
+	}
+	return offenders
+}
+
+// tlsVersions are the -min-tls values, in protocol order.
+var tlsVersions = []string{"1.0", "1.1", "1.2", "1.3"}
+
+// parseMinTLS turns a -min-tls value such as "1.2" or "TLSv1.2" into the
+// version name used in reports.
+func parseMinTLS(s string) (string, error) {
+	version := strings.TrimPrefix(s, "TLSv")
+	for _, v := range tlsVersions {
+		if v == version {
+			return "TLSv" + v, nil
+		}
+	}
+	return "", fmt.Errorf("invalid -min-tls %q: want one of %s", s, strings.Join(tlsVersions, ", "))
+}
+
+// belowMinTLS returns the versions offered on port that are older than
+// min, a version name such as "TLSv1.2".
+func belowMinTLS(port sslparse.Port, min string) []string {
+	var below []string
+	for _, v := range port.TLS.Versions() {
+		if v.Name == min {
+			break
+		}
+		if len(v.Data.Ciphers) > 0 {
+			below = append(below, v.Name)
+		}
+	}
+	return below
+}
+
+// applyMinTLS records on every port the versions offered below min.
+func applyMinTLS(hosts sslparse.Hosts, min string) {
+	for h := range hosts.Hosts {
+		for p := range hosts.Hosts[h].Ports {
+			port := &hosts.Hosts[h].Ports[p]
+			port.BelowMinTLS = belowMinTLS(*port, min)
+		}
+	}
+}
+
+// versionsBelowMin describes every port offering a version below -min-tls,
+// as recorded by applyMinTLS.
+func versionsBelowMin(hosts sslparse.Hosts) []string {
+	var offenders []string
+	for _, host := range hosts.Hosts {
+		for _, port := range host.Ports {
+			for _, v := range port.BelowMinTLS {
+				offenders = append(offenders, fmt.Sprintf("%s:%d offers %s", host.IP, port.ID, v))
+			}
+		}
+	}
+	return offenders
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"nmap-example/pkg/sslparse"
+)
+
+func TestCipherAllowlist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowed.txt")
+	list := "# approved suites\n" +
+		"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 (secp256r1) - A\n" +
+		"tls_akE_with_aes_128_gcm_sha256\n"
+	if err := os.WriteFile(path, []byte(list), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	allowed, err := readAllowedCiphers(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hosts := sslparse.Hosts{Hosts: []sslparse.HostInfo{{IP: "10.0.0.1", Ports: []sslparse.Port{
+		{ID: 443, TLS: sslparse.TLSVersions{
+			TLS12: ciphers("TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"),
+			TLS13: ciphers("TLS_AKE_WITH_AES_128_GCM_SHA256"),
+		}},
+		{ID: 8443, TLS: sslparse.TLSVersions{
+			TLS12: ciphers("TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_AES_128_CBC_SHA"),
+		}},
+	}}}}
+	allowed.apply(hosts)
+
+	want := []string{"10.0.0.1:8443 offers TLS_RSA_WITH_AES_128_CBC_SHA with TLSv1.2"}
+	if got := disallowedCiphers(hosts); !reflect.DeepEqual(got, want) {
+		t.Errorf("disallowedCiphers = %q, want %q", got, want)
+	}
+	if got := hosts.Hosts[0].Ports[0].TLS.TLS12.DisallowedCiphers; got != nil {
+		t.Errorf("allowed-only port has disallowed ciphers %q", got)
+	}
+
+	cfg := &config{failOnPolicy: true}
+	if v := cfg.violations(hosts); len(v) != 1 {
+		t.Errorf("violations = %q, want 1", v)
+	}
+}
+
+func TestBelowMinTLS(t *testing.T) {
+	port := sslparse.Port{ID: 443, TLS: sslparse.TLSVersions{
+		TLS10: ciphers("TLS_RSA_WITH_AES_128_CBC_SHA"),
+		TLS12: ciphers("TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"),
+	}}
+	for min, want := range map[string][]string{
+		"TLSv1.0": nil,
+		"TLSv1.1": {"TLSv1.0"},
+		"TLSv1.2": {"TLSv1.0"},
+		"TLSv1.3": {"TLSv1.0", "TLSv1.2"},
+	} {
+		if got := belowMinTLS(port, min); !reflect.DeepEqual(got, want) {
+			t.Errorf("belowMinTLS(-min-tls %s) = %q, want %q", min, got, want)
+		}
+	}
+
+	hosts := sslparse.Hosts{Hosts: []sslparse.HostInfo{{IP: "10.0.0.1", Ports: []sslparse.Port{port}}}}
+	applyMinTLS(hosts, "TLSv1.2")
+	if got, want := versionsBelowMin(hosts), []string{"10.0.0.1:443 offers TLSv1.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("versionsBelowMin = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// heartbeatInterval is how long reportProgress waits for a progress update
+// before logging a heartbeat instead.
+const heartbeatInterval = 15 * time.Second
+
+// reportProgress logs the completion percentages received on updates, with
+// an ETA extrapolated from the elapsed time, until updates is closed or stop
+// is signalled. Updates are throttled to whole-percent steps. When no update
+// arrives for interval, e.g. because nmap does not report progress for the
+// current phase, a heartbeat is logged so that the scan does not look hung.
+func reportProgress(updates <-chan float32, stop <-chan struct{}, interval time.Duration) {
+	start := time.Now()
+	last := float32(-1)
+	heartbeat := time.NewTimer(interval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case percent, ok := <-updates:
+			if !ok {
+				return
+			}
+			if percent-last < 1 {
+				continue
+			}
+			last = percent
+			slog.Info("scan progress", "percent", fmt.Sprintf("%.0f", percent), "eta", eta(time.Since(start), percent))
+			heartbeat.Reset(interval)
+		case <-heartbeat.C:
+			slog.Info("scan still running", "elapsed", time.Since(start).Round(time.Second))
+			heartbeat.Reset(interval)
+		case <-stop:
+			// Keep draining so the sender, see sslscan.RunWatch, is
+			// never left blocked on a send.
+			go func() {
+				for range updates {
+				}
+			}()
+			return
+		}
+	}
+}
+
+// eta estimates the remaining time from the elapsed time and the completion
+// percentage. It returns zero while the percentage is unknown.
+func eta(elapsed time.Duration, percent float32) time.Duration {
+	if percent <= 0 || percent >= 100 {
+		return 0
+	}
+	remaining := float64(elapsed) * float64(100-percent) / float64(percent)
+	return time.Duration(remaining).Round(time.Second)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureLogs sends the default logger's output to the returned buffer
+// for the rest of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	old := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(old) })
+	var b bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&b, nil)))
+	return &b
+}
+
+func TestReportProgressHeartbeat(t *testing.T) {
+	logs := captureLogs(t)
+	updates, stop := make(chan float32), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		reportProgress(updates, stop, 10*time.Millisecond)
+		close(done)
+	}()
+
+	updates <- 40
+	// No further update arrives, as while nmap runs scripts.
+	time.Sleep(50 * time.Millisecond)
+	close(stop)
+	<-done
+
+	out := logs.String()
+	if !strings.Contains(out, "scan progress") || !strings.Contains(out, "percent=40") {
+		t.Errorf("progress update not logged:\n%s", out)
+	}
+	if !strings.Contains(out, "scan still running") {
+		t.Errorf("no heartbeat logged after the interval:\n%s", out)
+	}
+}
+
+func TestETA(t *testing.T) {
+	if got := eta(time.Minute, 25); got != 3*time.Minute {
+		t.Errorf("eta(1m, 25%%) = %s, want 3m", got)
+	}
+	for _, percent := range []float32{0, 100} {
+		if got := eta(time.Minute, percent); got != 0 {
+			t.Errorf("eta(1m, %v%%) = %s, want 0", percent, got)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// writePromFile writes hosts as Prometheus metrics for the node_exporter
+// textfile collector. The file is written under a temporary name and
+// renamed into place, so the collector never reads a partial file.
+func writePromFile(path string, hosts sslparse.Hosts) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-"+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := writePromMetrics(f, hosts, time.Now()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// CreateTemp makes the file private; the collector may run as another
+	// user.
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// writePromMetrics renders per-port gauges for every port with TLS data,
+// including clean ones so that fixed problems drop back to zero, plus the
+// time of the scan. now is used when the report has no scan start time.
+func writePromMetrics(w io.Writer, hosts sslparse.Hosts, now time.Time) error {
+	var b strings.Builder
+	b.WriteString("# HELP tls_deprecated_protocols Number of deprecated protocol versions (SSLv3, TLS 1.0, 1.1) offered on the port.\n")
+	b.WriteString("# TYPE tls_deprecated_protocols gauge\n")
+	var weak strings.Builder
+	weak.WriteString("# HELP tls_weak_ciphers Number of weak cipher suites offered on the port.\n")
+	weak.WriteString("# TYPE tls_weak_ciphers gauge\n")
+
+	for _, host := range hosts.Hosts {
+		for _, port := range host.Ports {
+			if len(port.TLS.Offered()) == 0 {
+				continue
+			}
+			deprecated, weakCiphers := 0, 0
+			for _, v := range port.TLS.Versions() {
+				if v.Deprecated() && len(v.Data.Ciphers) > 0 {
+					deprecated++
+				}
+				weakCiphers += len(v.Data.WeakCiphers)
+			}
+			labels := fmt.Sprintf(`{host="%s",port="%d",protocol="%s"}`,
+				promEscape(host.IP), port.ID, promEscape(port.Protocol))
+			fmt.Fprintf(&b, "tls_deprecated_protocols%s %d\n", labels, deprecated)
+			fmt.Fprintf(&weak, "tls_weak_ciphers%s %d\n", labels, weakCiphers)
+		}
+	}
+	b.WriteString(weak.String())
+
+	scanned := now
+	if hosts.Meta != nil && !hosts.Meta.StartedAt.IsZero() {
+		scanned = hosts.Meta.StartedAt
+	}
+	b.WriteString("# HELP tls_scan_timestamp_seconds Unix time at which the scan started.\n")
+	b.WriteString("# TYPE tls_scan_timestamp_seconds gauge\n")
+	b.WriteString("tls_scan_timestamp_seconds " + strconv.FormatInt(scanned.Unix(), 10) + "\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// promEscape escapes a label value for the Prometheus text format.
+func promEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nmap-example/pkg/sslparse"
+)
+
+func TestWritePromMetrics(t *testing.T) {
+	tls10 := ciphers("TLS_RSA_WITH_RC4_128_SHA", "TLS_RSA_WITH_AES_128_CBC_SHA")
+	tls10.WeakCiphers = []string{"TLS_RSA_WITH_RC4_128_SHA"}
+	hosts := sslparse.Hosts{Hosts: []sslparse.HostInfo{{
+		IP: "10.0.0.1",
+		Ports: []sslparse.Port{
+			{ID: 80, Protocol: "tcp"},
+			{ID: 443, Protocol: "tcp", TLS: sslparse.TLSVersions{
+				TLS10: tls10,
+				TLS12: ciphers("TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"),
+			}},
+			{ID: 8443, Protocol: "tcp", TLS: sslparse.TLSVersions{
+				TLS13: ciphers("TLS_AES_128_GCM_SHA256"),
+			}},
+		},
+	}}}
+
+	var b bytes.Buffer
+	if err := writePromMetrics(&b, hosts, time.Unix(1700000000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP tls_deprecated_protocols Number of deprecated protocol versions (SSLv3, TLS 1.0, 1.1) offered on the port.
+# TYPE tls_deprecated_protocols gauge
+tls_deprecated_protocols{host="10.0.0.1",port="443",protocol="tcp"} 1
+tls_deprecated_protocols{host="10.0.0.1",port="8443",protocol="tcp"} 0
+# HELP tls_weak_ciphers Number of weak cipher suites offered on the port.
+# TYPE tls_weak_ciphers gauge
+tls_weak_ciphers{host="10.0.0.1",port="443",protocol="tcp"} 1
+tls_weak_ciphers{host="10.0.0.1",port="8443",protocol="tcp"} 0
+# HELP tls_scan_timestamp_seconds Unix time at which the scan started.
+# TYPE tls_scan_timestamp_seconds gauge
+tls_scan_timestamp_seconds 1700000000
+`
+	if got := b.String(); got != want {
+		t.Errorf("metrics =\n%s\nwant\n%s", got, want)
+	}
+
+	// The scan start time wins over now when the report has one.
+	b.Reset()
+	hosts.Meta = &sslparse.ScanMeta{StartedAt: time.Unix(1600000000, 0)}
+	if err := writePromMetrics(&b, hosts, time.Unix(1700000000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(b.String(), "\ntls_scan_timestamp_seconds 1600000000\n") {
+		t.Errorf("metrics do not use the scan start time:\n%s", b.String())
+	}
+}
+
+func TestPromEscape(t *testing.T) {
+	if got, want := promEscape("a\"b\\c\nd"), `a\"b\\c\nd`; got != want {
+		t.Errorf("promEscape = %s, want %s", got, want)
+	}
+}
+
+func TestWritePromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tls.prom")
+	if err := writePromFile(path, testHosts(t)); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o644 {
+		t.Errorf("mode = %v, want 0644", mode)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the metrics file", len(entries))
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+)
+
+// resumeScan continues an interrupted nmap scan from its normal (-oN) or
+// grepable (-oG) output file.
+//
+// nmap refuses any other argument next to --resume, including the -oX -
+// that the nmap library always adds, so nmap is run directly here. The
+// resumed scan appends to the output files of the original run rather than
+// producing a report; pass its XML output to -xml afterwards to report on
+// it.
+func (cfg *config) resumeScan(ctx context.Context) error {
+	binary := cfg.nmapPath
+	if binary == "" {
+		var err error
+		if binary, err = exec.LookPath("nmap"); err != nil {
+			return fmt.Errorf("resuming scan: %w", err)
+		}
+	}
+
+	slog.Info("resuming scan", "path", cfg.resumeFile)
+	cmd := exec.CommandContext(ctx, binary, "--resume", cfg.resumeFile)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("resuming scan: %w", err)
+	}
+	slog.Info("scan resumed to completion; report on its XML output with -xml")
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResume(t *testing.T) {
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	nmapPath := filepath.Join(dir, "nmap")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\n"
+	if err := os.WriteFile(nmapPath, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	resumeFile := filepath.Join(dir, "scan.gnmap")
+	if err := os.WriteFile(resumeFile, []byte("# Nmap 7.94 scan initiated\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := runMain(t, "-nmap-path", nmapPath, "-resume", resumeFile); err != nil {
+		t.Fatal(err)
+	}
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nothing but --resume may be passed to nmap.
+	if got, want := strings.TrimSpace(string(args)), "--resume "+resumeFile; got != want {
+		t.Errorf("nmap args = %q, want %q", got, want)
+	}
+}
+
+func TestResumeValidation(t *testing.T) {
+	for _, args := range [][]string{
+		{"-resume", filepath.Join(t.TempDir(), "missing.gnmap")},
+		{"-resume", "testdata/scan.xml", "-xml", "testdata/scan.xml"},
+		{"-resume", "testdata/scan.xml", "-targets", "example.com", "-dry-run"},
+	} {
+		if _, _, err := runMain(t, args...); err == nil {
+			t.Errorf("%q: want an error", args)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// s3PutObjectAPI is the part of the S3 client used for uploads, so that
+// the client can be replaced.
+type s3PutObjectAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// parseS3URI splits an "s3://bucket/key" URI into its bucket and key.
+func parseS3URI(uri string) (bucket, key string, err error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid -s3 %q: %w", uri, err)
+	}
+	key = strings.TrimPrefix(u.Path, "/")
+	if u.Scheme != "s3" || u.Host == "" || key == "" || strings.HasSuffix(key, "/") {
+		return "", "", fmt.Errorf("invalid -s3 %q: want s3://bucket/key", uri)
+	}
+	return u.Host, key, nil
+}
+
+// uploadS3 stores hosts as a JSON report at uri, using the standard AWS
+// credential and region resolution (environment, shared config, instance
+// roles).
+func uploadS3(ctx context.Context, uri string, compact bool, hosts sslparse.Hosts) error {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("loading AWS configuration: %w", err)
+	}
+	return putS3Report(ctx, s3.NewFromConfig(awsCfg), uri, compact, hosts)
+}
+
+func putS3Report(ctx context.Context, client s3PutObjectAPI, uri string, compact bool, hosts sslparse.Hosts) error {
+	bucket, key, err := parseS3URI(uri)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	if err := writeJSON(&body, hosts, compact); err != nil {
+		return err
+	}
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body.Bytes()),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// fakeS3 records the objects put into it.
+type fakeS3 struct {
+	input *s3.PutObjectInput
+	body  []byte
+	err   error
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	f.input = params
+	body, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.body = body
+	return &s3.PutObjectOutput{}, f.err
+}
+
+func TestPutS3Report(t *testing.T) {
+	client := &fakeS3{}
+	hosts := testHosts(t)
+	if err := putS3Report(context.Background(), client, "s3://reports/tls/latest.json", true, hosts); err != nil {
+		t.Fatal(err)
+	}
+	in := client.input
+	if aws.ToString(in.Bucket) != "reports" || aws.ToString(in.Key) != "tls/latest.json" {
+		t.Errorf("uploaded to %s/%s, want reports/tls/latest.json", aws.ToString(in.Bucket), aws.ToString(in.Key))
+	}
+	if aws.ToString(in.ContentType) != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", aws.ToString(in.ContentType))
+	}
+	var uploaded sslparse.Hosts
+	if err := json.Unmarshal(client.body, &uploaded); err != nil {
+		t.Fatalf("uploaded body is not a JSON report: %v", err)
+	}
+	if len(uploaded.Hosts) != len(hosts.Hosts) {
+		t.Errorf("uploaded %d hosts, want %d", len(uploaded.Hosts), len(hosts.Hosts))
+	}
+
+	client.err = errors.New("access denied")
+	if err := putS3Report(context.Background(), client, "s3://reports/latest.json", true, hosts); err == nil {
+		t.Error("failed upload: got no error")
+	}
+}
+
+func TestParseS3URI(t *testing.T) {
+	bucket, key, err := parseS3URI("s3://reports/tls/latest.json")
+	if err != nil || bucket != "reports" || key != "tls/latest.json" {
+		t.Errorf("parseS3URI = %q, %q, %v, want reports, tls/latest.json", bucket, key, err)
+	}
+	for _, bad := range []string{"https://reports/latest.json", "s3://reports", "s3://reports/", "s3:///latest.json", "s3://reports/tls/"} {
+		if _, _, err := parseS3URI(bad); err == nil {
+			t.Errorf("parseS3URI(%q): got no error", bad)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	"nmap-example/pkg/sslparse"
+)
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string            `json:"id"`
+	Name                 string            `json:"name"`
+	ShortDescription     sarifMessage      `json:"shortDescription"`
+	DefaultConfiguration sarifRuleDefaults `json:"defaultConfiguration"`
+}
+
+type sarifRuleDefaults struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// sarifRules lists the rules findings can map to, in ruleIndex order.
+var sarifRules = []sarifRule{
+	{
+		ID:                   ruleDeprecatedTLS,
+		Name:                 "DeprecatedTLSVersion",
+		ShortDescription:     sarifMessage{Text: "A deprecated protocol version (SSLv3, TLS 1.0 or 1.1) is offered"},
+		DefaultConfiguration: sarifRuleDefaults{Level: "warning"},
+	},
+	{
+		ID:                   ruleWeakCipher,
+		Name:                 "WeakCipherSuite",
+		ShortDescription:     sarifMessage{Text: "A weak cipher suite (RC4, DES, 3DES, NULL, EXPORT or MD5) is offered"},
+		DefaultConfiguration: sarifRuleDefaults{Level: "error"},
+	},
+	{
+		ID:                   ruleDisallowedCipher,
+		Name:                 "DisallowedCipherSuite",
+		ShortDescription:     sarifMessage{Text: "A cipher suite missing from the approved allowlist is offered"},
+		DefaultConfiguration: sarifRuleDefaults{Level: "error"},
+	},
+	{
+		ID:                   ruleBelowMinTLS,
+		Name:                 "BelowMinimumTLSVersion",
+		ShortDescription:     sarifMessage{Text: "A TLS version older than the required minimum is offered"},
+		DefaultConfiguration: sarifRuleDefaults{Level: "error"},
+	},
+}
+
+// writeSARIF renders every finding as a SARIF 2.1.0 result located at the
+// host:port it was found on.
+func writeSARIF(w io.Writer, hosts sslparse.Hosts) error {
+	ruleIndex := make(map[string]int, len(sarifRules))
+	for i, rule := range sarifRules {
+		ruleIndex[rule.ID] = i
+	}
+
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "nmap-example", Rules: sarifRules}},
+		Results: []sarifResult{},
+	}
+	for _, host := range hosts.Hosts {
+		for _, port := range host.Ports {
+			endpoint := net.JoinHostPort(host.IP, strconv.Itoa(int(port.ID)))
+			uri := port.Protocol + "://" + endpoint
+			for _, f := range portFindings(port) {
+				i := ruleIndex[f.rule]
+				run.Results = append(run.Results, sarifResult{
+					RuleID:    f.rule,
+					RuleIndex: i,
+					Level:     sarifRules[i].DefaultConfiguration.Level,
+					Message:   sarifMessage{Text: fmt.Sprintf("%s %s", endpoint, f.message)},
+					Locations: []sarifLocation{{
+						PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: uri}},
+						LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: endpoint, Kind: "endpoint"}},
+					}},
+				})
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
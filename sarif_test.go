@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	var b bytes.Buffer
+	if err := writeSARIF(&b, testHosts(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Decode into local types rather than the writer's own, so that a
+	// renamed JSON key shows up as a failure.
+	var log struct {
+		Schema  string `json:"$schema"`
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Level     string `json:"level"`
+				Message   struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(b.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+
+	if log.Version != "2.1.0" || log.Schema != sarifSchema {
+		t.Errorf("version, schema = %q, %q, want 2.1.0, %s", log.Version, log.Schema, sarifSchema)
+	}
+	if len(log.Runs) != 1 {
+		t.Fatalf("got %d runs, want 1", len(log.Runs))
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "nmap-example" {
+		t.Errorf("driver name = %q, want nmap-example", run.Tool.Driver.Name)
+	}
+
+	// The fixture offers TLSv1.0 and two weak ciphers on 443.
+	counts := make(map[string]int)
+	for _, r := range run.Results {
+		counts[r.RuleID]++
+		rules := run.Tool.Driver.Rules
+		if r.RuleIndex < 0 || r.RuleIndex >= len(rules) || rules[r.RuleIndex].ID != r.RuleID {
+			t.Errorf("result %q has ruleIndex %d, which is not its rule", r.RuleID, r.RuleIndex)
+		}
+		if r.Level == "" || r.Message.Text == "" {
+			t.Errorf("result %q has no level or message", r.RuleID)
+		}
+		if len(r.Locations) != 1 || r.Locations[0].PhysicalLocation.ArtifactLocation.URI != "tcp://93.184.216.34:443" {
+			t.Errorf("result %q locations = %+v, want tcp://93.184.216.34:443", r.RuleID, r.Locations)
+		}
+	}
+	if counts[ruleDeprecatedTLS] != 1 || counts[ruleWeakCipher] != 2 || len(run.Results) != 3 {
+		t.Errorf("results per rule = %v, want 1 %s and 2 %s", counts, ruleDeprecatedTLS, ruleWeakCipher)
+	}
+}
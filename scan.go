@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	nmap "github.com/Ullaakut/nmap/v3"
+	"golang.org/x/sync/errgroup"
+
+	"nmap-example/pkg/sslscan"
+)
+
+// scan runs nmap with the configured options, or loads a previous scan when
+// -xml is set, in which case nmap is not invoked at all.
+//
+// Targets are split into batches of -batch-size, and up to -concurrency
+// batches are scanned in parallel. The per-batch results are merged in batch
+// order so the report does not depend on which scanner finished first.
+// -endpoints add batches of their own, see batches.
+func (cfg *config) scan(ctx context.Context) (*nmap.Run, *[]string, error) {
+	if cfg.xmlFile != "" {
+		slog.Info("loading previous scan", "path", cfg.xmlFile)
+		result := &nmap.Run{}
+		if err := result.FromFile(cfg.xmlFile); err != nil {
+			return nil, nil, fmt.Errorf("reading XML scan %s: %w", cfg.xmlFile, err)
+		}
+		return result, nil, nil
+	}
+
+	batches := cfg.batches()
+	if len(batches) == 1 {
+		return cfg.scanBatch(ctx, batches[0])
+	}
+
+	runs := make([]*nmap.Run, len(batches))
+	warnings := make([]*[]string, len(batches))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(cfg.concurrency)
+	for i, batch := range batches {
+		i, batch := i, batch
+		g.Go(func() error {
+			var err error
+			runs[i], warnings[i], err = cfg.scanBatch(gctx, batch)
+			return err
+		})
+	}
+	err := g.Wait()
+
+	result, merged := mergeRuns(runs, warnings)
+	return result, merged, err
+}
+
+// scanJob is a single nmap invocation: a set of targets scanned on the
+// same ports. A nil ports uses -ports or -top-ports.
+type scanJob struct {
+	targets []string
+	ports   []string
+}
+
+// batches splits the work into nmap invocations: the -targets in batches
+// of -batch-size, then the -endpoints hosts grouped by identical port
+// lists, each group batched the same way.
+func (cfg *config) batches() []scanJob {
+	var batches []scanJob
+	if len(cfg.targets) > 0 || len(cfg.endpoints) == 0 {
+		for _, targets := range batchTargets(cfg.targets, cfg.batchSize) {
+			batches = append(batches, scanJob{targets: targets})
+		}
+	}
+	for _, group := range groupEndpoints(cfg.endpoints) {
+		for _, targets := range batchTargets(group.hosts, cfg.batchSize) {
+			batches = append(batches, scanJob{targets: targets, ports: group.ports})
+		}
+	}
+	return batches
+}
+
+func (cfg *config) scanBatch(ctx context.Context, batch scanJob) (*nmap.Run, *[]string, error) {
+	targets := batch.targets
+	slog.Info("starting scan", "targets", strings.Join(targets, ","))
+	var onHost func(nmap.Host)
+	if cfg.live != nil {
+		onHost = cfg.live.batch()
+	}
+	// Every attempt gets a fresh scanner: sslscan.Run points the scanner
+	// at its own output file, so a scanner cannot be run twice.
+	result, warnings, err := cfg.runWithRetry(ctx, func() (*nmap.Run, *[]string, error) {
+		scanner, err := sslscan.NewScanner(ctx, cfg.scanOptions(batch))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// sslscan.RunWatch keeps the hosts nmap finished when the timeout
+		// kills it.
+		watch := sslscan.Watch{Host: onHost}
+		if cfg.progress {
+			updates, stop := make(chan float32), make(chan struct{})
+			defer close(stop)
+			go reportProgress(updates, stop, heartbeatInterval)
+			watch.Progress = updates
+		}
+		return sslscan.RunWatch(ctx, scanner, watch)
+	})
+	if err != nil {
+		return result, warnings, fmt.Errorf("running scan: %w", err)
+	}
+	return result, warnings, nil
+}
+
+// printCommands writes the nmap command line of every batch to w, one per
+// line, without running nmap. Progress reporting is left out, as it only
+// adds --stats-every to the real run.
+func (cfg *config) printCommands(ctx context.Context, w io.Writer) error {
+	for _, batch := range cfg.batches() {
+		scanner, err := sslscan.NewScanner(ctx, cfg.scanOptions(batch))
+		if err != nil {
+			return err
+		}
+		binary := "nmap"
+		if cfg.nmapPath != "" {
+			binary = shellQuote(cfg.nmapPath)
+		}
+		args := []string{binary}
+		for _, arg := range scanner.Args() {
+			args = append(args, shellQuote(arg))
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(args, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shellQuote single-quotes arg if it contains anything a POSIX shell would
+// interpret, so printed commands can be pasted into a terminal.
+func shellQuote(arg string) string {
+	if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:,=+@%") == "" {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// retryBackoff is the delay before the first retry; it doubles after each
+// further failed attempt, up to maxRetryBackoff. They are variables so
+// that tests can shorten them.
+var (
+	retryBackoff    = time.Second
+	maxRetryBackoff = time.Minute
+)
+
+// maxRetries is the largest -retries accepted.
+const maxRetries = 10
+
+// runWithRetry calls run until it succeeds or -retries additional attempts
+// have failed, waiting with exponential backoff in between. Warnings on a
+// successful run are not a reason to retry. It gives up early once ctx is
+// done, returning the last error.
+func (cfg *config) runWithRetry(ctx context.Context, run func() (*nmap.Run, *[]string, error)) (*nmap.Run, *[]string, error) {
+	for attempt := 0; ; attempt++ {
+		result, warnings, err := run()
+		if err == nil || attempt >= cfg.retries || ctx.Err() != nil {
+			return result, warnings, err
+		}
+
+		delay := backoff(attempt)
+		slog.Warn("scan attempt failed, retrying", "attempt", attempt+1, "err", err, "delay", delay)
+		select {
+		case <-ctx.Done():
+			return result, warnings, err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoff returns the delay after the given failed attempt, counted from
+// zero, without overflowing for large attempts.
+func backoff(attempt int) time.Duration {
+	delay := retryBackoff
+	for i := 0; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	return delay
+}
+
+// batchTargets splits targets into consecutive batches of at most size
+// entries. A size of zero or less puts every target in a single batch.
+func batchTargets(targets []string, size int) [][]string {
+	if size <= 0 || size >= len(targets) {
+		return [][]string{targets}
+	}
+	var batches [][]string
+	for start := 0; start < len(targets); start += size {
+		end := start + size
+		if end > len(targets) {
+			end = len(targets)
+		}
+		batches = append(batches, targets[start:end])
+	}
+	return batches
+}
+
+// mergeRuns combines per-batch results into a single run. The metadata of
+// the first available run is kept and the hosts of every run are appended
+// in order. Nil runs, e.g. from batches that failed, are skipped.
+func mergeRuns(runs []*nmap.Run, warnings []*[]string) (*nmap.Run, *[]string) {
+	var merged *nmap.Run
+	for _, run := range runs {
+		if run == nil {
+			continue
+		}
+		if merged == nil {
+			base := *run
+			base.Hosts = nil
+			merged = &base
+		}
+		merged.Hosts = append(merged.Hosts, run.Hosts...)
+
+		// Stretch the run window so the metadata covers every batch.
+		if time.Time(run.Start).Before(time.Time(merged.Start)) {
+			merged.Start = run.Start
+		}
+		if time.Time(run.Stats.Finished.Time).After(time.Time(merged.Stats.Finished.Time)) {
+			merged.Stats.Finished = run.Stats.Finished
+		}
+	}
+	if merged != nil {
+		start, end := time.Time(merged.Start), time.Time(merged.Stats.Finished.Time)
+		merged.Stats.Finished.Elapsed = float32(end.Sub(start).Seconds())
+	}
+
+	allWarnings := []string{}
+	for _, w := range warnings {
+		if w != nil {
+			allWarnings = append(allWarnings, *w...)
+		}
+	}
+	return merged, &allWarnings
+}
+
+// scanOptions translates the configuration into scan options for the
+// given batch. The overall -timeout is enforced by run, not per batch.
+func (cfg *config) scanOptions(batch scanJob) sslscan.ScanOptions {
+	opts := sslscan.ScanOptions{
+		Targets:  batch.targets,
+		Ports:    cfg.ports,
+		TopPorts: cfg.topPorts,
+		Scripts:  cfg.scripts,
+	}
+	if batch.ports != nil {
+		opts.Ports, opts.TopPorts = batch.ports, 0
+	}
+	if cfg.udp {
+		opts.Options = append(opts.Options, nmap.WithUDPScan())
+		// -sU on its own replaces the default TCP scan, so ask for a SYN
+		// scan explicitly when both protocols are wanted.
+		if cfg.tcp {
+			opts.Options = append(opts.Options, nmap.WithSYNScan())
+		}
+	}
+	if cfg.hostRetries >= 0 {
+		opts.Options = append(opts.Options, nmap.WithMaxRetries(cfg.hostRetries))
+	}
+	if cfg.maxHostgroup > 0 {
+		opts.Options = append(opts.Options, nmap.WithMaxHostgroup(cfg.maxHostgroup))
+	}
+	if cfg.hostTimeout > 0 {
+		opts.Options = append(opts.Options, nmap.WithHostTimeout(cfg.hostTimeout))
+	}
+	if cfg.minRate > 0 {
+		opts.Options = append(opts.Options, nmap.WithMinRate(cfg.minRate))
+	}
+	if cfg.maxRate > 0 {
+		opts.Options = append(opts.Options, nmap.WithMaxRate(cfg.maxRate))
+	}
+	if len(cfg.exclude) > 0 {
+		opts.Options = append(opts.Options, nmap.WithTargetExclusion(strings.Join(cfg.exclude, ",")))
+	}
+	if cfg.timing >= 0 {
+		opts.Options = append(opts.Options, nmap.WithTimingTemplate(nmap.Timing(cfg.timing)))
+	}
+	if cfg.skipDiscovery {
+		opts.Options = append(opts.Options, nmap.WithSkipHostDiscovery())
+	}
+	if cfg.iface != "" {
+		opts.Options = append(opts.Options, nmap.WithInterface(cfg.iface))
+	}
+	if cfg.sourceIP != "" {
+		opts.Options = append(opts.Options, nmap.WithSpoofIPAddress(cfg.sourceIP))
+	}
+	if cfg.fragment {
+		opts.Options = append(opts.Options, nmap.WithFragmentPackets())
+	}
+	if len(cfg.decoys) > 0 {
+		opts.Options = append(opts.Options, nmap.WithDecoys(cfg.decoys...))
+	}
+	if len(cfg.dnsServers) > 0 {
+		opts.Options = append(opts.Options, nmap.WithCustomDNSServers(cfg.dnsServers...))
+	}
+	if cfg.noDNS {
+		opts.Options = append(opts.Options, nmap.WithDisabledDNSResolution())
+	}
+	if cfg.ipv6 {
+		opts.Options = append(opts.Options, nmap.WithIPv6Scanning())
+	}
+	if cfg.serviceInfo {
+		opts.Options = append(opts.Options, nmap.WithServiceInfo())
+	}
+	if cfg.osDetection {
+		opts.Options = append(opts.Options, nmap.WithOSDetection())
+	}
+	if cfg.nmapPath != "" {
+		opts.Options = append(opts.Options, nmap.WithBinaryPath(cfg.nmapPath))
+	}
+	for _, args := range cfg.extraArgs {
+		opts.Options = append(opts.Options, nmap.WithCustomArguments(strings.Fields(args)...))
+	}
+	return opts
+}
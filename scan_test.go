@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	nmap "github.com/Ullaakut/nmap/v3"
+
+	"nmap-example/pkg/sslscan"
+)
+
+// scanArgs returns the nmap arguments cfg produces for a scan of
+// example.com, using the fake nmap in testdata so that nmap need not be
+// installed.
+func scanArgs(t *testing.T, cfg *config) []string {
+	t.Helper()
+	cfg.nmapPath = "testdata/fake-nmap"
+	scanner, err := sslscan.NewScanner(context.Background(), cfg.scanOptions(scanJob{targets: []string{"example.com"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scanner.Args()
+}
+
+// flagArgs returns the nmap arguments produced by parsing the command line
+// args plus -targets example.com.
+func flagArgs(t *testing.T, args ...string) []string {
+	t.Helper()
+	resetFlags(t)
+	cfg, err := parseFlags(append(args, "-targets", "example.com"))
+	if err != nil {
+		t.Fatalf("parseFlags(%q): %v", args, err)
+	}
+	return scanArgs(t, cfg)
+}
+
+// hasArgs reports whether want appears in args as consecutive arguments.
+func hasArgs(args []string, want ...string) bool {
+	for i := 0; i+len(want) <= len(args); i++ {
+		if reflect.DeepEqual(args[i:i+len(want)], want) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBatchTargets(t *testing.T) {
+	targets := []string{"a", "b", "c", "d", "e"}
+	tests := []struct {
+		size int
+		want [][]string
+	}{
+		{0, [][]string{{"a", "b", "c", "d", "e"}}},
+		{2, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}},
+		{5, [][]string{{"a", "b", "c", "d", "e"}}},
+		{10, [][]string{{"a", "b", "c", "d", "e"}}},
+	}
+	for _, tt := range tests {
+		if got := batchTargets(targets, tt.size); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("batchTargets(size %d) = %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestMergeRuns(t *testing.T) {
+	at := func(sec int64) time.Time { return time.Unix(1700000000+sec, 0) }
+	batch := func(start, end int64, ips ...string) *nmap.Run {
+		run := &nmap.Run{Version: "7.94", Start: nmap.Timestamp(at(start))}
+		run.Stats.Finished.Time = nmap.Timestamp(at(end))
+		for _, ip := range ips {
+			run.Hosts = append(run.Hosts, nmap.Host{Addresses: []nmap.Address{{Addr: ip, AddrType: "ipv4"}}})
+		}
+		return run
+	}
+	runs := []*nmap.Run{batch(10, 20, "10.0.0.1", "10.0.0.2"), nil, batch(5, 30, "10.0.0.3")}
+	warnings := []*[]string{{"first"}, nil, {"second", "third"}}
+
+	merged, mergedWarnings := mergeRuns(runs, warnings)
+
+	var ips []string
+	for _, host := range merged.Hosts {
+		ips = append(ips, host.Addresses[0].Addr)
+	}
+	if want := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}; !reflect.DeepEqual(ips, want) {
+		t.Errorf("hosts = %q, want %q in batch order", ips, want)
+	}
+	if start := time.Time(merged.Start); !start.Equal(at(5)) {
+		t.Errorf("start = %s, want the earliest batch start %s", start, at(5))
+	}
+	if end := time.Time(merged.Stats.Finished.Time); !end.Equal(at(30)) {
+		t.Errorf("finished = %s, want the latest batch end %s", end, at(30))
+	}
+	if elapsed := merged.Stats.Finished.Elapsed; elapsed != 25 {
+		t.Errorf("elapsed = %v, want 25", elapsed)
+	}
+	if want := []string{"first", "second", "third"}; !reflect.DeepEqual(*mergedWarnings, want) {
+		t.Errorf("warnings = %q, want %q", *mergedWarnings, want)
+	}
+	if len(runs[0].Hosts) != 2 {
+		t.Error("mergeRuns modified the hosts of the first run")
+	}
+
+	if merged, _ := mergeRuns([]*nmap.Run{nil}, nil); merged != nil {
+		t.Errorf("merging only failed batches = %+v, want nil", merged)
+	}
+}
+
+func TestRunWithRetry(t *testing.T) {
+	oldBackoff, oldMax := retryBackoff, maxRetryBackoff
+	retryBackoff, maxRetryBackoff = time.Millisecond, 4*time.Millisecond
+	t.Cleanup(func() { retryBackoff, maxRetryBackoff = oldBackoff, oldMax })
+
+	calls := 0
+	flaky := func() (*nmap.Run, *[]string, error) {
+		calls++
+		if calls <= 2 {
+			return nil, nil, errors.New("nmap failed")
+		}
+		return &nmap.Run{Version: "7.94"}, &[]string{}, nil
+	}
+
+	cfg := &config{retries: 3}
+	result, _, err := cfg.runWithRetry(context.Background(), flaky)
+	if err != nil {
+		t.Fatalf("runWithRetry: %v", err)
+	}
+	if calls != 3 || result == nil || result.Version != "7.94" {
+		t.Errorf("got %d calls and result %+v, want the third call's result", calls, result)
+	}
+
+	calls = 0
+	cfg.retries = 1
+	if _, _, err := cfg.runWithRetry(context.Background(), flaky); err == nil || calls != 2 {
+		t.Errorf("with -retries 1: got %d calls and err %v, want 2 calls and an error", calls, err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
+	for attempt, w := range want {
+		if got := backoff(attempt); got != w {
+			t.Errorf("backoff(%d) = %s, want %s", attempt, got, w)
+		}
+	}
+	// retryBackoff << 64 would overflow to zero.
+	for _, attempt := range []int{6, 40, 64, 1000} {
+		if got := backoff(attempt); got != maxRetryBackoff {
+			t.Errorf("backoff(%d) = %s, want the maximum %s", attempt, got, maxRetryBackoff)
+		}
+	}
+}
+
+func TestSkipDiscoveryArgs(t *testing.T) {
+	if args := scanArgs(t, &config{}); hasArgs(args, "-Pn") {
+		t.Errorf("args without -skip-discovery = %q, want no -Pn", args)
+	}
+	if args := scanArgs(t, &config{skipDiscovery: true}); !hasArgs(args, "-Pn") {
+		t.Errorf("args with -skip-discovery = %q, want -Pn", args)
+	}
+}
+
+func TestExcludeArgs(t *testing.T) {
+	args := scanArgs(t, &config{exclude: stringList{"10.0.0.5", "10.0.1.0/24"}})
+	if !hasArgs(args, "--exclude", "10.0.0.5,10.0.1.0/24") {
+		t.Errorf("args = %q, want --exclude 10.0.0.5,10.0.1.0/24", args)
+	}
+}
+
+func TestPrintCommands(t *testing.T) {
+	cfg := &config{
+		targets:     stringList{"example.com", "10.0.0.0/30"},
+		ports:       stringList{"443", "8443"},
+		scripts:     stringList{"ssl-enum-ciphers", "ssl-cert"},
+		batchSize:   1,
+		timing:      -1,
+		hostRetries: -1,
+		nmapPath:    "testdata/fake-nmap",
+	}
+	var b bytes.Buffer
+	if err := cfg.printCommands(context.Background(), &b); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"testdata/fake-nmap example.com --script=ssl-enum-ciphers,ssl-cert -p 443,8443\n" +
+		"testdata/fake-nmap 10.0.0.0/30 --script=ssl-enum-ciphers,ssl-cert -p 443,8443\n"
+	if got := b.String(); got != want {
+		t.Errorf("printCommands =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	for arg, want := range map[string]string{
+		"example.com":       "example.com",
+		"--script=ssl-cert": "--script=ssl-cert",
+		"":                  "''",
+		"a b":               "'a b'",
+		"it's":              `'it'\''s'`,
+		"$(reboot)":         "'$(reboot)'",
+	} {
+		if got := shellQuote(arg); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", arg, got, want)
+		}
+	}
+}
+
+func TestExtraArgs(t *testing.T) {
+	args := scanArgs(t, &config{extraArgs: repeatedFlag{"--reason  --version-intensity 5", "--badsum"}})
+	if !hasArgs(args, "--reason", "--version-intensity", "5", "--badsum") {
+		t.Errorf("args = %q, want the -extra-args split on whitespace, at the end", args)
+	}
+}
+
+func TestHostRetriesArgs(t *testing.T) {
+	if args := flagArgs(t); hasArgs(args, "--max-retries") {
+		t.Errorf("args without -host-retries = %q, want no --max-retries", args)
+	}
+	for _, retries := range []string{"0", "3"} {
+		if args := flagArgs(t, "-host-retries", retries); !hasArgs(args, "--max-retries", retries) {
+			t.Errorf("args with -host-retries %s = %q, want --max-retries %s", retries, args, retries)
+		}
+	}
+	resetFlags(t)
+	if _, err := parseFlags([]string{"-host-retries", "-2", "-targets", "example.com"}); err == nil {
+		t.Error("-host-retries -2: want an error")
+	}
+}
+
+func TestNoDNSArgs(t *testing.T) {
+	if args := flagArgs(t); hasArgs(args, "-n") {
+		t.Errorf("args without -no-dns = %q, want no -n", args)
+	}
+	if args := flagArgs(t, "-no-dns"); !hasArgs(args, "-n") {
+		t.Errorf("args with -no-dns = %q, want -n", args)
+	}
+}
+
+func TestInterfaceAndSourceIPArgs(t *testing.T) {
+	args := flagArgs(t, "-interface", "eth1", "-source-ip", "192.0.2.10")
+	if !hasArgs(args, "-e", "eth1") || !hasArgs(args, "-S", "192.0.2.10") {
+		t.Errorf("args = %q, want -e eth1 and -S 192.0.2.10", args)
+	}
+	if args := flagArgs(t); hasArgs(args, "-e") || hasArgs(args, "-S") {
+		t.Errorf("args without -interface and -source-ip = %q, want neither -e nor -S", args)
+	}
+	for _, bad := range [][]string{
+		{"-interface", ""},
+		{"-interface", "eth 1"},
+		{"-source-ip", "not-an-ip"},
+	} {
+		resetFlags(t)
+		if _, err := parseFlags(append(bad, "-targets", "example.com")); err == nil {
+			t.Errorf("%q: want an error", bad)
+		}
+	}
+}
+
+func TestHostTimeoutArgs(t *testing.T) {
+	if args := flagArgs(t); hasArgs(args, "--host-timeout") {
+		t.Errorf("args without -host-timeout = %q, want no --host-timeout", args)
+	}
+	if args := flagArgs(t, "-host-timeout", "2m"); !hasArgs(args, "--host-timeout", "120000ms") {
+		t.Errorf("args with -host-timeout 2m = %q, want --host-timeout 120000ms", args)
+	}
+	// -timeout 0 disables the overall timeout, so any -host-timeout fits.
+	if args := flagArgs(t, "-host-timeout", "1h", "-timeout", "0"); !hasArgs(args, "--host-timeout", "3600000ms") {
+		t.Errorf("args with -timeout 0 = %q, want --host-timeout 3600000ms", args)
+	}
+	for _, bad := range [][]string{
+		{"-host-timeout", "5m"},
+		{"-host-timeout", "10m", "-timeout", "5m"},
+		{"-host-timeout", "-1s"},
+		{"-host-timeout", "1us"},
+	} {
+		resetFlags(t)
+		if _, err := parseFlags(append(bad, "-targets", "example.com")); err == nil {
+			t.Errorf("%q: want an error", bad)
+		}
+	}
+}
+
+func TestDNSServersArgs(t *testing.T) {
+	args := flagArgs(t, "-dns-servers", "192.0.2.53,2001:db8::53", "-dns-servers", "192.0.2.54")
+	if !hasArgs(args, "--dns-servers", "192.0.2.53,2001:db8::53,192.0.2.54") {
+		t.Errorf("args = %q, want --dns-servers 192.0.2.53,2001:db8::53,192.0.2.54", args)
+	}
+	for _, bad := range [][]string{
+		{"-dns-servers", "dns.example"},
+		{"-dns-servers", "192.0.2.53,300.0.0.1"},
+		{"-dns-servers", "192.0.2.53", "-no-dns"},
+	} {
+		resetFlags(t)
+		if _, err := parseFlags(append(bad, "-targets", "example.com")); err == nil {
+			t.Errorf("%q: want an error", bad)
+		}
+	}
+}
+
+func TestFragmentAndDecoysArgs(t *testing.T) {
+	args := flagArgs(t, "-fragment", "-decoys", "192.0.2.1,ME", "-decoys", "2001:db8::2")
+	if !hasArgs(args, "-f") {
+		t.Errorf("args with -fragment = %q, want -f", args)
+	}
+	if !hasArgs(args, "-D", "192.0.2.1,ME,2001:db8::2") {
+		t.Errorf("args = %q, want -D 192.0.2.1,ME,2001:db8::2", args)
+	}
+	if args := flagArgs(t); hasArgs(args, "-f") || hasArgs(args, "-D") {
+		t.Errorf("args without -fragment and -decoys = %q, want neither -f nor -D", args)
+	}
+	if args := flagArgs(t, "-decoys", "me"); !hasArgs(args, "-D", "me") {
+		t.Errorf("args with -decoys me = %q, want ME to be accepted in any case", args)
+	}
+	for _, decoys := range []string{"decoy.example", "192.0.2.1,RND", "192.0.2.300"} {
+		resetFlags(t)
+		if _, err := parseFlags([]string{"-decoys", decoys, "-targets", "example.com"}); err == nil {
+			t.Errorf("-decoys %s: want an error", decoys)
+		}
+	}
+}
+
+func TestMaxHostgroupArgs(t *testing.T) {
+	if args := flagArgs(t); hasArgs(args, "--max-hostgroup") {
+		t.Errorf("args without -max-hostgroup = %q, want no --max-hostgroup", args)
+	}
+	if args := flagArgs(t, "-max-hostgroup", "16"); !hasArgs(args, "--max-hostgroup", "16") {
+		t.Errorf("args with -max-hostgroup 16 = %q, want --max-hostgroup 16", args)
+	}
+	for _, n := range []string{"0", "-4"} {
+		resetFlags(t)
+		if _, err := parseFlags([]string{"-max-hostgroup", n, "-targets", "example.com"}); err == nil {
+			t.Errorf("-max-hostgroup %s: want an error", n)
+		}
+	}
+}
+
+func TestProtocolArgs(t *testing.T) {
+	tests := []struct {
+		args      []string
+		udp, syn  bool
+		protocols string
+	}{
+		{nil, false, false, "TCP"},
+		{[]string{"-udp"}, true, false, "UDP only"},
+		{[]string{"-udp", "-tcp"}, true, true, "TCP and UDP"},
+		{[]string{"-udp", "-tcp=false"}, true, false, "UDP only"},
+	}
+	for _, tt := range tests {
+		args := flagArgs(t, tt.args...)
+		if hasArgs(args, "-sU") != tt.udp || hasArgs(args, "-sS") != tt.syn {
+			t.Errorf("args with %q = %q, want a %s scan", tt.args, args, tt.protocols)
+		}
+	}
+	resetFlags(t)
+	if _, err := parseFlags([]string{"-tcp=false", "-targets", "example.com"}); err == nil {
+		t.Error("-tcp=false without -udp: want an error")
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// slackPayload is the body of a Slack incoming webhook request.
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+// notifySlack posts a summary of the TLS findings in hosts, see
+// portFindings, to a Slack incoming webhook. A clean scan is only
+// reported when always is set.
+func notifySlack(ctx context.Context, url string, always bool, hosts sslparse.Hosts) error {
+	text, found := slackMessage(hosts)
+	if !found && !always {
+		return nil
+	}
+	return postJSON(ctx, url, nil, slackPayload{Text: text})
+}
+
+// slackMessage formats the findings of hosts as Slack mrkdwn, with one
+// bullet per affected port, and reports whether there were any.
+func slackMessage(hosts sslparse.Hosts) (string, bool) {
+	var lines []string
+	for _, host := range hosts.Hosts {
+		for _, port := range host.Ports {
+			if issues := portIssues(port); len(issues) > 0 {
+				lines = append(lines, fmt.Sprintf("• `%s:%d/%s`: %s", host.IP, port.ID, port.Protocol, strings.Join(issues, "; ")))
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return fmt.Sprintf(":white_check_mark: TLS scan of %d hosts found no TLS findings.", len(hosts.Hosts)), false
+	}
+	header := fmt.Sprintf(":warning: TLS scan found TLS findings on %d ports:", len(lines))
+	return header + "\n" + strings.Join(lines, "\n"), true
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nmap-example/pkg/sslparse"
+)
+
+func TestNotifySlack(t *testing.T) {
+	var texts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload slackPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		texts = append(texts, payload.Text)
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	if err := notifySlack(ctx, srv.URL, false, testHosts(t)); err != nil {
+		t.Fatal(err)
+	}
+	want := ":warning: TLS scan found TLS findings on 1 ports:\n" +
+		"• `93.184.216.34:443/tcp`: offers deprecated TLSv1.0; " +
+		"offers weak cipher TLS_RSA_WITH_3DES_EDE_CBC_SHA with TLSv1.0; " +
+		"offers weak cipher TLS_RSA_WITH_RC4_128_SHA with TLSv1.0"
+	if len(texts) != 1 || texts[0] != want {
+		t.Errorf("messages = %q, want %q", texts, want)
+	}
+
+	// A port only below -min-tls is reported with the same header.
+	texts = nil
+	belowMin := sslparse.Hosts{Hosts: []sslparse.HostInfo{{IP: "10.0.0.1", Ports: []sslparse.Port{
+		{ID: 443, Protocol: "tcp", BelowMinTLS: []string{"TLSv1.2"}},
+	}}}}
+	if err := notifySlack(ctx, srv.URL, false, belowMin); err != nil {
+		t.Fatal(err)
+	}
+	want = ":warning: TLS scan found TLS findings on 1 ports:\n" +
+		"• `10.0.0.1:443/tcp`: offers TLSv1.2, below the minimum TLS version"
+	if len(texts) != 1 || texts[0] != want {
+		t.Errorf("messages = %q, want %q", texts, want)
+	}
+
+	// A clean scan is only posted with -slack-always.
+	clean := sslparse.Hosts{Hosts: []sslparse.HostInfo{{IP: "10.0.0.1"}}}
+	texts = nil
+	if err := notifySlack(ctx, srv.URL, false, clean); err != nil {
+		t.Fatal(err)
+	}
+	if len(texts) != 0 {
+		t.Errorf("clean scan posted %q without -slack-always", texts)
+	}
+	if err := notifySlack(ctx, srv.URL, true, clean); err != nil {
+		t.Fatal(err)
+	}
+	if len(texts) != 1 || texts[0] != ":white_check_mark: TLS scan of 1 hosts found no TLS findings." {
+		t.Errorf("clean scan with -slack-always posted %q", texts)
+	}
+}
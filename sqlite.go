@@ -0,0 +1,130 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// sqliteSchema creates the tables used for historical tracking. Hosts and
+// ports are upserted so they keep a stable id across scans, while every
+// scan adds its own port_scans and ciphers rows.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS scans (
+	id         INTEGER PRIMARY KEY,
+	started_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS hosts (
+	id        INTEGER PRIMARY KEY,
+	ip        TEXT NOT NULL UNIQUE,
+	last_seen TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS ports (
+	id       INTEGER PRIMARY KEY,
+	host_id  INTEGER NOT NULL REFERENCES hosts(id),
+	port     INTEGER NOT NULL,
+	protocol TEXT NOT NULL,
+	service  TEXT NOT NULL,
+	UNIQUE (host_id, port, protocol)
+);
+CREATE TABLE IF NOT EXISTS port_scans (
+	scan_id        INTEGER NOT NULL REFERENCES scans(id),
+	port_id        INTEGER NOT NULL REFERENCES ports(id),
+	state          TEXT NOT NULL,
+	grade          TEXT NOT NULL,
+	least_strength TEXT NOT NULL,
+	PRIMARY KEY (scan_id, port_id)
+);
+CREATE TABLE IF NOT EXISTS ciphers (
+	scan_id     INTEGER NOT NULL REFERENCES scans(id),
+	port_id     INTEGER NOT NULL REFERENCES ports(id),
+	tls_version TEXT NOT NULL,
+	cipher      TEXT NOT NULL,
+	strength    TEXT NOT NULL
+);
+`
+
+// storeSQLite opens (or creates) the SQLite database at path and stores
+// hosts in it.
+func storeSQLite(path string, hosts sslparse.Hosts) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	if err := store(db, hosts); err != nil {
+		db.Close()
+		return err
+	}
+	return db.Close()
+}
+
+// store records hosts as a new scan in db, creating the schema if needed.
+// The scan timestamp is the nmap start time, or now for reports without
+// scan metadata. Everything is written in a single transaction.
+func store(db *sql.DB, hosts sslparse.Hosts) error {
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("creating schema: %w", err)
+	}
+
+	startedAt := time.Now().UTC()
+	if hosts.Meta != nil && !hosts.Meta.StartedAt.IsZero() {
+		startedAt = hosts.Meta.StartedAt.UTC()
+	}
+	ts := startedAt.Format(time.RFC3339)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO scans (started_at) VALUES (?)`, ts)
+	if err != nil {
+		return err
+	}
+	scanID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, host := range hosts.Hosts {
+		var hostID int64
+		err := tx.QueryRow(`INSERT INTO hosts (ip, last_seen) VALUES (?, ?)
+			ON CONFLICT (ip) DO UPDATE SET last_seen = excluded.last_seen
+			RETURNING id`, host.IP, ts).Scan(&hostID)
+		if err != nil {
+			return fmt.Errorf("storing host %s: %w", host.IP, err)
+		}
+
+		for _, port := range host.Ports {
+			var portID int64
+			err := tx.QueryRow(`INSERT INTO ports (host_id, port, protocol, service) VALUES (?, ?, ?, ?)
+				ON CONFLICT (host_id, port, protocol) DO UPDATE SET service = excluded.service
+				RETURNING id`, hostID, port.ID, port.Protocol, port.Service).Scan(&portID)
+			if err != nil {
+				return fmt.Errorf("storing port %s:%d: %w", host.IP, port.ID, err)
+			}
+
+			_, err = tx.Exec(`INSERT INTO port_scans (scan_id, port_id, state, grade, least_strength) VALUES (?, ?, ?, ?, ?)`,
+				scanID, portID, port.State, port.Grade, port.TLS.Strength)
+			if err != nil {
+				return fmt.Errorf("storing port %s:%d: %w", host.IP, port.ID, err)
+			}
+
+			for _, v := range port.TLS.Versions() {
+				for _, cipher := range v.Data.Ciphers {
+					_, err := tx.Exec(`INSERT INTO ciphers (scan_id, port_id, tls_version, cipher, strength) VALUES (?, ?, ?, ?, ?)`,
+						scanID, portID, v.Name, cipher.Name, cipher.Strength)
+					if err != nil {
+						return fmt.Errorf("storing ciphers for %s:%d: %w", host.IP, port.ID, err)
+					}
+				}
+			}
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// Every connection to :memory: is a database of its own.
+	db.SetMaxOpenConns(1)
+
+	hosts := testHosts(t)
+	for i := 0; i < 2; i++ {
+		if err := store(db, hosts); err != nil {
+			t.Fatalf("store %d: %v", i+1, err)
+		}
+	}
+
+	count := func(query string) int {
+		t.Helper()
+		var n int
+		if err := db.QueryRow(query).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	// Hosts and ports are upserted; scans and their rows are not.
+	for query, want := range map[string]int{
+		`SELECT COUNT(*) FROM scans`:      2,
+		`SELECT COUNT(*) FROM hosts`:      2,
+		`SELECT COUNT(*) FROM ports`:      2,
+		`SELECT COUNT(*) FROM port_scans`: 4,
+		`SELECT COUNT(*) FROM ciphers`:    14,
+	} {
+		if got := count(query); got != want {
+			t.Errorf("%s = %d, want %d", query, got, want)
+		}
+	}
+
+	var grade, strength, startedAt string
+	err = db.QueryRow(`SELECT ps.grade, ps.least_strength, s.started_at
+		FROM port_scans ps
+		JOIN ports p ON p.id = ps.port_id
+		JOIN hosts h ON h.id = p.host_id
+		JOIN scans s ON s.id = ps.scan_id
+		WHERE h.ip = '93.184.216.34' AND p.port = 443
+		ORDER BY s.id LIMIT 1`).Scan(&grade, &strength, &startedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grade != "F" || strength != "C" || startedAt != "2023-11-14T22:13:20Z" {
+		t.Errorf("443 row = %s, %s, %s, want F, C, 2023-11-14T22:13:20Z", grade, strength, startedAt)
+	}
+}
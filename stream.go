@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	nmap "github.com/Ullaakut/nmap/v3"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// streamFormats are the formats -stream can write host by host.
+var streamFormats = []string{"json", "ndjson"}
+
+// streamHosts parses run one host at a time and writes each host to w as
+// soon as it is parsed, so that the whole report is never held in memory.
+// cfg.filter is applied to every parsed host as a single-host report; the
+// hosts and summary it returns are what gets written.
+//
+// For the json format the report has the same fields as the buffered
+// writer, but the summary and errors follow the hosts since they are only
+// known at the end. Hosts are written compactly, one per line. Unlike
+// ParseRun, a host that appears more than once in run is written once per
+// entry, since merging would mean holding on to every host.
+func (cfg *config) streamHosts(w io.Writer, run *nmap.Run, format string) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	if format == "json" {
+		meta, err := json.Marshal(sslparse.RunMeta(run))
+		if err != nil {
+			return err
+		}
+		note, err := json.Marshal(sslparse.OfferedNote)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "{\"scan\":%s,\"note\":%s,\"hosts\":[", meta, note)
+	} else if format != "ndjson" {
+		return fmt.Errorf("format %q cannot be streamed", format)
+	}
+
+	var summary sslparse.Summary
+	errs := []sslparse.HostError{}
+	written := 0
+	for _, host := range run.Hosts {
+		info, hostErrs, ok := sslparse.ParseHostChecked(host)
+		errs = append(errs, hostErrs...)
+		if !ok {
+			continue
+		}
+		filtered := cfg.filter(sslparse.Hosts{Hosts: []sslparse.HostInfo{info}})
+		summary.Add(filtered.Summary)
+
+		for _, info := range filtered.Hosts {
+			if format == "ndjson" {
+				if err := enc.Encode(info); err != nil {
+					return err
+				}
+				continue
+			}
+			data, err := json.Marshal(info)
+			if err != nil {
+				return err
+			}
+			if written > 0 {
+				bw.WriteString(",")
+			}
+			bw.WriteString("\n")
+			bw.Write(data)
+			written++
+		}
+	}
+
+	if format == "json" {
+		tail, err := json.Marshal(struct {
+			Summary sslparse.Summary     `json:"summary"`
+			Errors  []sslparse.HostError `json:"errors,omitempty"`
+		}{summary, errs})
+		if err != nil {
+			return err
+		}
+		// Splice the summary and errors into the open report object.
+		fmt.Fprintf(bw, "\n],%s\n", tail[1:])
+	}
+	return bw.Flush()
+}
+
+// writeStream streams the report for result in format to path, or to
+// stdout when path is empty.
+func (cfg *config) writeStream(path, format string, result *nmap.Run) error {
+	if path == "" {
+		return cfg.streamHosts(os.Stdout, result, format)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := cfg.streamHosts(f, result, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// streamsLive reports whether the only report is an ndjson one that no
+// flag needs in full. Such a report is written while nmap runs, each host
+// as soon as nmap has finished it, see hostStream.
+func (cfg *config) streamsLive() bool {
+	return len(cfg.reports) == 1 && cfg.reports[0].format == "ndjson" && cfg.needsReport() == ""
+}
+
+// hostStream writes the ndjson report while nmap runs. Every host is
+// parsed, passed through cfg.filter as a single-host report and written
+// on a line of its own as soon as nmap has finished it. As with
+// streamHosts, a host scanned by several batches is written once per
+// batch.
+type hostStream struct {
+	cfg *config
+	f   *os.File
+
+	mu  sync.Mutex
+	enc *json.Encoder
+	// err is the first write error; no host is written after it.
+	err error
+}
+
+// openHostStream creates the report at path, or writes it to stdout when
+// path is empty.
+func (cfg *config) openHostStream(path string) (*hostStream, error) {
+	if path == "" {
+		return &hostStream{cfg: cfg, enc: json.NewEncoder(os.Stdout)}, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &hostStream{cfg: cfg, f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// batch returns the sslscan.Watch.Host callback of one batch. runWithRetry
+// scans the batch again after a failed attempt, so the hosts an earlier
+// attempt already wrote are skipped.
+func (s *hostStream) batch() func(nmap.Host) {
+	seen := make(map[string]bool)
+	return func(host nmap.Host) {
+		info, errs, ok := sslparse.ParseHostChecked(host)
+		if info.IP != "" {
+			if seen[info.IP] {
+				return
+			}
+			seen[info.IP] = true
+		}
+		for _, hostErr := range errs {
+			slog.Warn("parse error", "ip", hostErr.IP, "err", hostErr.Message)
+		}
+		if ok {
+			s.write(info)
+		}
+	}
+}
+
+func (s *hostStream) write(info sslparse.HostInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	filtered := s.cfg.filter(sslparse.Hosts{Hosts: []sslparse.HostInfo{info}})
+	for _, info := range filtered.Hosts {
+		if s.err != nil {
+			return
+		}
+		s.err = s.enc.Encode(info)
+	}
+}
+
+// close closes the report and returns the first error writing it. It may
+// be called more than once.
+func (s *hostStream) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f != nil {
+		if err := s.f.Close(); s.err == nil {
+			s.err = err
+		}
+		s.f = nil
+	}
+	return s.err
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	nmap "github.com/Ullaakut/nmap/v3"
+
+	"nmap-example/pkg/sslparse"
+)
+
+func TestNDJSONLines(t *testing.T) {
+	stdout, _, err := runMain(t, "-xml", "testdata/scan.xml", "-format", "ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want one per host:\n%s", len(lines), stdout)
+	}
+	for i, line := range lines {
+		var host sslparse.HostInfo
+		if err := json.Unmarshal([]byte(line), &host); err != nil {
+			t.Errorf("line %d does not unmarshal on its own: %v\n%s", i+1, err, line)
+		}
+		if host.IP == "" {
+			t.Errorf("line %d has no host IP: %s", i+1, line)
+		}
+	}
+}
+
+func TestNDJSONMatchesJSON(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath, ndjsonPath := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.ndjson")
+	_, _, err := runMain(t, "-xml", "testdata/scan.xml", "-sort", "-format", "json,ndjson", "-o", jsonPath+","+ndjsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report sslparse.Hosts
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(ndjsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hosts []sslparse.HostInfo
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		var host sslparse.HostInfo
+		if err := json.Unmarshal([]byte(line), &host); err != nil {
+			t.Fatal(err)
+		}
+		hosts = append(hosts, host)
+	}
+	if !reflect.DeepEqual(hosts, report.Hosts) {
+		t.Errorf("ndjson hosts differ from the json report:\n%+v\n%+v", hosts, report.Hosts)
+	}
+}
+
+func TestNDJSONWrittenDuringScan(t *testing.T) {
+	// slow-nmap writes its first host and then hangs until the timeout
+	// kills it, so the host has to be in the report while nmap runs.
+	t.Setenv("FAKE_NMAP_XML", "testdata/scan.xml")
+	path := filepath.Join(t.TempDir(), "report.ndjson")
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := runMain(t, "-nmap-path", "pkg/sslscan/testdata/slow-nmap", "-targets", "example.com",
+			"-timeout", "3s", "-format", "ndjson", "-o", path)
+		done <- err
+	}()
+
+	var line string
+	for deadline := time.Now().Add(2 * time.Second); line == "" && time.Now().Before(deadline); {
+		time.Sleep(50 * time.Millisecond)
+		if data, err := os.ReadFile(path); err == nil {
+			line, _, _ = strings.Cut(string(data), "\n")
+		}
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("scan finished before the first line was read: %v", err)
+	default:
+	}
+	var host sslparse.HostInfo
+	if err := json.Unmarshal([]byte(line), &host); err != nil {
+		t.Fatalf("first line %q: %v", line, err)
+	}
+	if host.IP != "93.184.216.34" {
+		t.Errorf("first host = %s, want 93.184.216.34", host.IP)
+	}
+	if err := <-done; err == nil || !strings.Contains(err.Error(), "scan interrupted") {
+		t.Errorf("err = %v, want the scan to be reported as interrupted", err)
+	}
+}
+
+// largeRun returns a scan of n copies of the TLS host in testdata/scan.xml,
+// each with its own IP.
+func largeRun(b *testing.B, n int) *nmap.Run {
+	b.Helper()
+	fixture := &nmap.Run{}
+	if err := fixture.FromFile("testdata/scan.xml"); err != nil {
+		b.Fatal(err)
+	}
+	run := &nmap.Run{Start: fixture.Start, Stats: fixture.Stats, Version: fixture.Version}
+	for i := 0; i < n; i++ {
+		host := fixture.Hosts[0]
+		host.Addresses = []nmap.Address{{Addr: fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff), AddrType: "ipv4"}}
+		run.Hosts = append(run.Hosts, host)
+	}
+	return run
+}
+
+// heapSampler is a writer that records the peak live heap while a report
+// is written to it. Sampling forces a garbage collection, so only every
+// 64th write is sampled, starting with the first.
+type heapSampler struct {
+	writes int
+	peak   uint64
+}
+
+func (s *heapSampler) Write(p []byte) (int, error) {
+	if s.writes%64 == 0 {
+		runtime.GC()
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		if m.HeapAlloc > s.peak {
+			s.peak = m.HeapAlloc
+		}
+	}
+	s.writes++
+	return len(p), nil
+}
+
+// benchmarkReport reports the allocations of write and, from one sampled
+// run outside the timed loop, the peak live heap above what the scan
+// itself holds as live-heap-bytes.
+func benchmarkReport(b *testing.B, write func(io.Writer, *nmap.Run) error) {
+	run := largeRun(b, 5000)
+
+	runtime.GC()
+	var base runtime.MemStats
+	runtime.ReadMemStats(&base)
+	sampler := &heapSampler{}
+	if err := write(sampler, run); err != nil {
+		b.Fatal(err)
+	}
+	live := uint64(0)
+	if sampler.peak > base.HeapAlloc {
+		live = sampler.peak - base.HeapAlloc
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := write(io.Discard, run); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(live), "live-heap-bytes")
+}
+
+func BenchmarkStreamHosts(b *testing.B) {
+	cfg := &config{}
+	benchmarkReport(b, func(w io.Writer, run *nmap.Run) error {
+		return cfg.streamHosts(w, run, "json")
+	})
+}
+
+// BenchmarkBuffered writes the same report the way -stream does not: the
+// whole scan is parsed first and then encoded in one piece.
+func BenchmarkBuffered(b *testing.B) {
+	benchmarkReport(b, func(w io.Writer, run *nmap.Run) error {
+		return writeReport(w, "json", reportOptions{compact: true}, sslparse.ParseRun(run))
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// ANSI escape sequences used to color grades.
+const (
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiRed    = "\x1b[31m"
+	ansiReset  = "\x1b[0m"
+)
+
+// writeTable prints an aligned, human-readable table with one row per port.
+// When color is set, grades are colored by severity. The grade is the last
+// column so that the escape sequences do not upset the alignment.
+func writeTable(w io.Writer, hosts sslparse.Hosts, color bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "IP\tPORT\tSERVICE\tSTATE\tTLS\tGRADE")
+	for _, host := range hosts.Hosts {
+		for _, port := range host.Ports {
+			grade := port.Grade
+			if color {
+				grade = colorGrade(grade)
+			}
+			fmt.Fprintf(tw, "%s\t%d/%s\t%s\t%s\t%s\t%s\n",
+				host.IP, port.ID, port.Protocol, port.Service, port.State,
+				strings.Join(port.TLS.Offered(), ","), grade)
+		}
+	}
+	return tw.Flush()
+}
+
+// colorGrade wraps grade in green for A and B, yellow for C and red for
+// anything worse. Ungraded ports are left as is.
+func colorGrade(grade string) string {
+	switch grade {
+	case "":
+		return grade
+	case "A", "B":
+		return ansiGreen + grade + ansiReset
+	case "C":
+		return ansiYellow + grade + ansiReset
+	default:
+		return ansiRed + grade + ansiReset
+	}
+}
+
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteTable(t *testing.T) {
+	var b bytes.Buffer
+	if err := writeTable(&b, testHosts(t), false); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"IP             PORT     SERVICE  STATE  TLS                      GRADE\n" +
+		"93.184.216.34  80/tcp   http     open                            \n" +
+		"93.184.216.34  443/tcp  https    open   TLSv1.0,TLSv1.2,TLSv1.3  F\n"
+	if got := b.String(); got != want {
+		t.Errorf("table =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteTableColor(t *testing.T) {
+	var b bytes.Buffer
+	if err := writeTable(&b, testHosts(t), true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "  "+ansiRed+"F"+ansiReset+"\n") {
+		t.Errorf("colored table has no red F grade:\n%q", b.String())
+	}
+
+	// Output that is not a terminal, as here, is never colored.
+	for _, args := range [][]string{{}, {"-no-color"}} {
+		stdout, _, err := runMain(t, append(args, "-xml", "testdata/scan.xml", "-format", "table")...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(stdout, "\x1b[") {
+			t.Errorf("%q: table has ANSI escape codes:\n%q", args, stdout)
+		}
+	}
+}
+
+func TestColorGrade(t *testing.T) {
+	for grade, want := range map[string]string{
+		"":  "",
+		"A": ansiGreen + "A" + ansiReset,
+		"B": ansiGreen + "B" + ansiReset,
+		"C": ansiYellow + "C" + ansiReset,
+		"D": ansiRed + "D" + ansiReset,
+		"F": ansiRed + "F" + ansiReset,
+	} {
+		if got := colorGrade(grade); got != want {
+			t.Errorf("colorGrade(%q) = %q, want %q", grade, got, want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// readTargetsFromFile returns the targets listed in path, one per line.
+func readTargetsFromFile(path string) ([]string, error) {
+	targets, err := readListFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading targets file: %w", err)
+	}
+	return targets, nil
+}
+
+// readListFile returns the entries listed in path, one per line.
+// Surrounding whitespace is trimmed, and blank lines and lines starting
+// with # are skipped.
+func readListFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// dedupe removes repeated entries from values, keeping the first occurrence.
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// normalizeTargets trims and lowercases targets, drops duplicates while
+// keeping the original order, and rejects entries that cannot be valid nmap
+// targets because they contain whitespace or control characters.
+func normalizeTargets(targets []string) ([]string, error) {
+	normalized := make([]string, 0, len(targets))
+	for _, target := range targets {
+		target = strings.ToLower(strings.TrimSpace(target))
+		if target == "" {
+			continue
+		}
+		for _, r := range target {
+			if unicode.IsSpace(r) || unicode.IsControl(r) {
+				return nil, fmt.Errorf("invalid target %q: contains whitespace or control characters", target)
+			}
+		}
+		normalized = append(normalized, target)
+	}
+	return dedupe(normalized), nil
+}
+
+// coveredTarget is a target left out by dropCovered, with the widest
+// target whose range includes it.
+type coveredTarget struct {
+	target, by string
+}
+
+// dropCovered removes the IP and CIDR targets that another CIDR target
+// already includes, e.g. 10.0.0.5 next to 10.0.0.0/24, since nmap would
+// scan and report such a host twice. Containment is checked on the
+// prefixes, so ranges are never expanded. Of two targets for the same
+// range, the first one is kept. Hostnames and nmap range syntax such as
+// 10.0.0.1-5 are left alone.
+func dropCovered(targets []string) (kept []string, dropped []coveredTarget) {
+	prefixes := make([]netip.Prefix, len(targets))
+	for i, target := range targets {
+		prefixes[i] = targetPrefix(target)
+	}
+	for i, target := range targets {
+		p := prefixes[i]
+		by := -1
+		for j, other := range prefixes {
+			if j == i || !p.IsValid() || !other.IsValid() {
+				continue
+			}
+			if other.Bits() > p.Bits() || !other.Contains(p.Addr()) {
+				continue
+			}
+			// Identical ranges cover each other; keep the first.
+			if other.Bits() == p.Bits() && j > i {
+				continue
+			}
+			if by < 0 || other.Bits() < prefixes[by].Bits() {
+				by = j
+			}
+		}
+		if by < 0 {
+			kept = append(kept, target)
+			continue
+		}
+		dropped = append(dropped, coveredTarget{target: target, by: targets[by]})
+	}
+	return kept, dropped
+}
+
+// targetPrefix parses an IP or CIDR target as a prefix, a single IP being
+// a full-length one. It returns the zero Prefix for anything else.
+func targetPrefix(target string) netip.Prefix {
+	if addr, err := netip.ParseAddr(target); err == nil {
+		return netip.PrefixFrom(addr, addr.BitLen())
+	}
+	if prefix, err := netip.ParsePrefix(target); err == nil {
+		return prefix.Masked()
+	}
+	return netip.Prefix{}
+}
+
+var (
+	hostnamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+	numericPattern  = regexp.MustCompile(`^[0-9.]+$`)
+)
+
+// validateExclusions checks that every -exclude entry is an IP address, a
+// CIDR range or a hostname. Dotted numbers that do not parse as an IPv4
+// address, such as 10.0.0.300, are rejected instead of being taken for a
+// hostname.
+func validateExclusions(exclusions []string) error {
+	for _, entry := range exclusions {
+		entry = strings.ToLower(entry)
+		if net.ParseIP(entry) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(entry); err == nil {
+			continue
+		}
+		if !numericPattern.MatchString(entry) && hostnamePattern.MatchString(entry) {
+			continue
+		}
+		return fmt.Errorf("invalid -exclude %q: want an IP address, CIDR range or hostname", entry)
+	}
+	return nil
+}
+
+// endpointGroup is a set of -endpoints hosts that share the same ports.
+type endpointGroup struct {
+	hosts []string
+	ports []string
+}
+
+// parseEndpoint splits a "host:port" -endpoints entry. IPv6 addresses are
+// written in brackets, e.g. "[2001:db8::1]:443".
+func parseEndpoint(entry string) (host, port string, err error) {
+	host, port, err = net.SplitHostPort(strings.TrimSpace(entry))
+	if err != nil || host == "" {
+		return "", "", fmt.Errorf("invalid -endpoints entry %q: want host:port", entry)
+	}
+	if _, err := parsePort(port); err != nil {
+		return "", "", fmt.Errorf("invalid -endpoints entry %q: %w", entry, err)
+	}
+	return strings.ToLower(host), port, nil
+}
+
+// validateEndpoints checks the syntax of every -endpoints entry.
+func validateEndpoints(endpoints []string) error {
+	for _, entry := range endpoints {
+		if _, _, err := parseEndpoint(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupEndpoints collects the ports of each host, then groups the hosts
+// with identical port lists so they can share one nmap invocation. Hosts,
+// ports and groups keep the order in which they were first listed.
+// Entries must have been checked with validateEndpoints.
+func groupEndpoints(endpoints []string) []endpointGroup {
+	var hosts []string
+	ports := make(map[string][]string)
+	for _, entry := range endpoints {
+		host, port, _ := parseEndpoint(entry)
+		if _, ok := ports[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		ports[host] = dedupe(append(ports[host], port))
+	}
+
+	var groups []endpointGroup
+	index := make(map[string]int)
+	for _, host := range hosts {
+		key := strings.Join(ports[host], ",")
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, endpointGroup{ports: ports[host]})
+		}
+		groups[i].hosts = append(groups[i].hosts, host)
+	}
+	return groups
+}
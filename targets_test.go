@@ -0,0 +1,127 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTargets(t *testing.T) {
+	got, err := normalizeTargets([]string{" Example.COM ", "10.0.0.1", "example.com", "", "10.0.0.0/24", "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"example.com", "10.0.0.1", "10.0.0.0/24"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeTargets = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"example .com", "host\tname", "10.0.0.1\x00", "evil\nhost"} {
+		if _, err := normalizeTargets([]string{"example.com", bad}); err == nil {
+			t.Errorf("normalizeTargets accepted %q", bad)
+		}
+	}
+}
+
+func TestValidateExclusions(t *testing.T) {
+	valid := []string{"10.0.0.5", "10.0.0.0/24", "2001:db8::1", "2001:db8::/64", "Host-1.Example.com", "localhost"}
+	if err := validateExclusions(valid); err != nil {
+		t.Errorf("validateExclusions(%q) = %v", valid, err)
+	}
+	for _, bad := range []string{"10.0.0.300", "10.0.0.0/33", "-bad.example.com", "bad_host", "a b", ""} {
+		if err := validateExclusions([]string{bad}); err == nil {
+			t.Errorf("validateExclusions accepted %q", bad)
+		}
+	}
+}
+
+func TestParseEndpoint(t *testing.T) {
+	for entry, want := range map[string][2]string{
+		"example.com:443":     {"example.com", "443"},
+		" Example.COM:8443 ":  {"example.com", "8443"},
+		"10.0.0.1:25":         {"10.0.0.1", "25"},
+		"[2001:db8::1]:443":   {"2001:db8::1", "443"},
+		"[2001:DB8::1]:65535": {"2001:db8::1", "65535"},
+	} {
+		host, port, err := parseEndpoint(entry)
+		if err != nil {
+			t.Errorf("parseEndpoint(%q): %v", entry, err)
+			continue
+		}
+		if host != want[0] || port != want[1] {
+			t.Errorf("parseEndpoint(%q) = %q, %q, want %q, %q", entry, host, port, want[0], want[1])
+		}
+	}
+	for _, entry := range []string{"example.com", ":443", "example.com:", "example.com:https", "example.com:65536", "2001:db8::1:443"} {
+		if _, _, err := parseEndpoint(entry); err == nil {
+			t.Errorf("parseEndpoint(%q) = nil error, want an error", entry)
+		}
+	}
+}
+
+func TestGroupEndpoints(t *testing.T) {
+	got := groupEndpoints([]string{
+		"a.example:443",
+		"b.example:8443",
+		"a.example:8443",
+		"c.example:443",
+		"A.example:443",
+		"d.example:443",
+		"d.example:8443",
+	})
+	want := []endpointGroup{
+		{hosts: []string{"a.example", "d.example"}, ports: []string{"443", "8443"}},
+		{hosts: []string{"b.example"}, ports: []string{"8443"}},
+		{hosts: []string{"c.example"}, ports: []string{"443"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groupEndpoints =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestBatchesWithEndpoints(t *testing.T) {
+	cfg := &config{
+		targets:   stringList{"10.0.0.1", "10.0.0.2"},
+		endpoints: stringList{"a.example:443", "b.example:443", "c.example:25"},
+		batchSize: 1,
+	}
+	want := []scanJob{
+		{targets: []string{"10.0.0.1"}},
+		{targets: []string{"10.0.0.2"}},
+		{targets: []string{"a.example"}, ports: []string{"443"}},
+		{targets: []string{"b.example"}, ports: []string{"443"}},
+		{targets: []string{"c.example"}, ports: []string{"25"}},
+	}
+	if got := cfg.batches(); !reflect.DeepEqual(got, want) {
+		t.Errorf("batches =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestDropCovered(t *testing.T) {
+	kept, dropped := dropCovered([]string{
+		"10.0.0.5",
+		"10.0.0.0/24",
+		"10.0.0.128/25",
+		"example.com",
+		"10.0.1.1-5",
+		"10.0.2.1",
+		"10.0.0.0/24",
+		"2001:db8::1",
+		"2001:db8::/64",
+	})
+	if want := []string{"10.0.0.0/24", "example.com", "10.0.1.1-5", "10.0.2.1", "2001:db8::/64"}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept = %q, want %q", kept, want)
+	}
+	want := []coveredTarget{
+		{target: "10.0.0.5", by: "10.0.0.0/24"},
+		{target: "10.0.0.128/25", by: "10.0.0.0/24"},
+		{target: "10.0.0.0/24", by: "10.0.0.0/24"},
+		{target: "2001:db8::1", by: "2001:db8::/64"},
+	}
+	if !reflect.DeepEqual(dropped, want) {
+		t.Errorf("dropped = %+v, want %+v", dropped, want)
+	}
+
+	if kept, dropped := dropCovered([]string{"10.0.0.5", "10.0.0.6"}); len(kept) != 2 || dropped != nil {
+		t.Errorf("distinct IPs: kept %q, dropped %+v, want both kept", kept, dropped)
+	}
+}
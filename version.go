@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// Build metadata, set at build time with e.g.
+//
+//	go build -ldflags "-X main.version=v1.2.3 -X main.commit=$(git rev-parse --short HEAD) -X main.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
+// printVersion writes the build metadata to w.
+func printVersion(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "nmap-example %s (commit %s, built %s)\n", version, commit, date)
+	return err
+}
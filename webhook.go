@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// postWebhook sends the JSON report to url. headers are "Name: value" pairs
+// added to the request, e.g. for authentication. Any non-2xx response is
+// reported as an error.
+func postWebhook(ctx context.Context, url string, headers []string, hosts sslparse.Hosts) error {
+	return postJSON(ctx, url, headers, hosts)
+}
+
+// postJSON POSTs v encoded as JSON to url with the given "Name: value"
+// headers, and fails on any non-2xx response.
+func postJSON(ctx context.Context, url string, headers []string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for _, h := range headers {
+		name, value, _ := strings.Cut(h, ":")
+		req.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nmap-example/pkg/sslparse"
+)
+
+func TestPostWebhook(t *testing.T) {
+	var (
+		gotMethod, gotType, gotAuth string
+		gotHosts                    sslparse.Hosts
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotType = r.Header.Get("Content-Type")
+		gotAuth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&gotHosts); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	hosts := testHosts(t)
+	err := postWebhook(context.Background(), srv.URL, []string{"Authorization: Bearer s3cret"}, hosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotMethod != http.MethodPost || gotType != "application/json" {
+		t.Errorf("request = %s with Content-Type %q, want a JSON POST", gotMethod, gotType)
+	}
+	if gotAuth != "Bearer s3cret" {
+		t.Errorf("Authorization = %q, want %q", gotAuth, "Bearer s3cret")
+	}
+	if len(gotHosts.Hosts) != len(hosts.Hosts) || gotHosts.Hosts[0].IP != hosts.Hosts[0].IP {
+		t.Errorf("body hosts = %+v, want the report's hosts", gotHosts.Hosts)
+	}
+}
+
+func TestPostWebhookErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	if err := postWebhook(context.Background(), srv.URL, nil, sslparse.Hosts{}); err == nil {
+		t.Error("403 response: got no error")
+	}
+}
+
+func TestWebhookAfterTimeout(t *testing.T) {
+	// The partial results of a scan that timed out are still delivered,
+	// although the scan's context is done by then.
+	var got sslparse.Hosts
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("FAKE_NMAP_XML", "testdata/scan.xml")
+	_, _, err := runMain(t, "-nmap-path", "pkg/sslscan/testdata/slow-nmap", "-targets", "example.com",
+		"-timeout", "300ms", "-webhook", srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "scan interrupted") {
+		t.Errorf("err = %v, want the scan to be reported as interrupted", err)
+	}
+	if len(got.Hosts) != 1 {
+		t.Errorf("webhook got %d hosts, want the 1 host nmap finished", len(got.Hosts))
+	}
+}
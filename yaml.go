@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"gopkg.in/yaml.v3"
+
+	"nmap-example/pkg/sslparse"
+)
+
+// writeYAML writes hosts as a YAML document. The report is encoded as JSON
+// first so that the YAML keys, their order and omitempty behaviour match
+// the json format exactly; since JSON is valid YAML, it is then re-encoded
+// in block style. The yaml struct tags in sslparse mirror the json ones, so
+// the document decodes back into sslparse.Hosts.
+func writeYAML(w io.Writer, hosts sslparse.Hosts) error {
+	data, err := json.Marshal(hosts)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	resetStyle(&doc)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// resetStyle clears the flow and quoting styles inherited from the JSON
+// input, letting the encoder pick the most readable style for each node.
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"nmap-example/pkg/sslparse"
+)
+
+func TestWriteYAMLRoundTrip(t *testing.T) {
+	hosts := testHosts(t)
+	var buf bytes.Buffer
+	if err := writeYAML(&buf, hosts); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded sslparse.Hosts
+	if err := yaml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("decoding YAML: %v\n%s", err, buf.String())
+	}
+	want, err := json.Marshal(hosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("YAML round trip changed the report:\ngot  %s\nwant %s", got, want)
+	}
+}